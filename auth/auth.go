@@ -79,4 +79,32 @@ func (auth *Auth) GetTlsConfigs() (*tls.Config, error) {
 	}
 
 	return tlsConf, nil
-}
\ No newline at end of file
+}
+
+func (auth *Auth) GetServerTlsConfigs(serverCert string, serverKey string) (*tls.Config, error) {
+	tlsConf := &tls.Config{}
+
+	//Server credentials
+	certData, err := tls.LoadX509KeyPair(serverCert, serverKey)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Failed to load server credentials: %s", err.Error()))
+	}
+	(*tlsConf).Certificates = []tls.Certificate{certData}
+
+	//CA cert validating client certificates, which are then required
+	if auth.CaCert != "" {
+		caCertContent, err := ioutil.ReadFile(auth.CaCert)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Failed to read root certificate file: %s", err.Error()))
+		}
+		roots := x509.NewCertPool()
+		ok := roots.AppendCertsFromPEM(caCertContent)
+		if !ok {
+			return nil, errors.New("Failed to parse root certificate authority")
+		}
+		(*tlsConf).ClientCAs = roots
+		(*tlsConf).ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return tlsConf, nil
+}
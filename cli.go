@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Ferlab-Ste-Justine/terracd/config"
+)
+
+type subcommand struct {
+	Name        string
+	Description string
+	Command     string
+}
+
+var subcommands = []subcommand{
+	{Name: "run", Description: "Execute the command of the configuration, in daemon mode if the configuration defines a daemon. This is the default if no subcommand is given."},
+	{Name: "plan", Description: "Execute the plan command, regardless of the command of the configuration.", Command: "plan"},
+	{Name: "apply", Description: "Execute the apply command, regardless of the command of the configuration.", Command: "apply"},
+	{Name: "drift", Description: "Execute the drift command, regardless of the command of the configuration.", Command: "drift"},
+	{Name: "destroy", Description: "Execute the destroy command, regardless of the command of the configuration.", Command: "destroy"},
+	{Name: "wait", Description: "Execute the wait command, regardless of the command of the configuration.", Command: "wait"},
+	{Name: "migrate_backend", Description: "Execute the migrate_backend command, regardless of the command of the configuration.", Command: "migrate_backend"},
+	{Name: "import", Description: "Execute the pending imports of the configuration and of the state operations files, regardless of the command of the configuration.", Command: "import"},
+	{Name: "state_mv", Description: "Execute the pending state moves of the configuration and of the state operations files, regardless of the command of the configuration.", Command: "state_mv"},
+	{Name: "state_rm", Description: "Execute the pending state removals of the configuration and of the state operations files, regardless of the command of the configuration.", Command: "state_rm"},
+	{Name: "validate-config", Description: "Validate the configuration without executing anything."},
+	{Name: "history", Description: "Print the runs recorded in the state, most recent first."},
+	{Name: "unlock", Description: "Force the release of the lock on the state, such as one left behind by an execution that crashed."},
+}
+
+type cliArgs struct {
+	Subcommand string
+	Overrides  config.Overrides
+}
+
+func getSubcommand(name string) (subcommand, bool) {
+	for _, sub := range subcommands {
+		if sub.Name == name {
+			return sub, true
+		}
+	}
+
+	return subcommand{}, false
+}
+
+func printUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: terracd [subcommand] [flags]\n\nSubcommands:\n")
+	for _, sub := range subcommands {
+		fmt.Fprintf(os.Stderr, "  %-16s %s\n", sub.Name, sub.Description)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun 'terracd <subcommand> -h' to list the flags of a subcommand.\n")
+}
+
+//Parses the command-line arguments. The subcommand defaults to run if the arguments start with a flag or are empty.
+func parseArgs(args []string) (cliArgs, error) {
+	parsed := cliArgs{Subcommand: "run"}
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		parsed.Subcommand = args[0]
+		args = args[1:]
+	}
+
+	sub, ok := getSubcommand(parsed.Subcommand)
+	if !ok {
+		if parsed.Subcommand == "help" {
+			printUsage()
+			return parsed, flag.ErrHelp
+		}
+
+		printUsage()
+		return parsed, errors.New(fmt.Sprintf("Unknown subcommand \"%s\"", parsed.Subcommand))
+	}
+
+	flags := flag.NewFlagSet(sub.Name, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: terracd %s [flags]\n\n%s\n\nFlags:\n", sub.Name, sub.Description)
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&parsed.Overrides.ConfigFile, "config", "", "Path of the configuration file. Defaults to the TERRACD_CONFIG_FILE environment variable or config.yml")
+	flags.StringVar(&parsed.Overrides.WorkingDirectory, "working-directory", "", "Overrides the working_directory field of the configuration")
+	flags.StringVar(&parsed.Overrides.DataPath, "data-path", "", "Overrides the data_path field of the configuration")
+	if sub.Command == "" {
+		flags.StringVar(&parsed.Overrides.Command, "command", "", "Overrides the command field of the configuration")
+	}
+
+	parseErr := flags.Parse(args)
+	if parseErr != nil {
+		return parsed, parseErr
+	}
+
+	if flags.NArg() > 0 {
+		return parsed, errors.New(fmt.Sprintf("Unexpected arguments for subcommand %s: %s", sub.Name, strings.Join(flags.Args(), " ")))
+	}
+
+	if sub.Command != "" {
+		parsed.Overrides.Command = sub.Command
+	}
+
+	return parsed, nil
+}
@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/Ferlab-Ste-Justine/terracd/config"
+	"github.com/Ferlab-Ste-Justine/terracd/recurrence"
+	"github.com/Ferlab-Ste-Justine/terracd/state"
+	"github.com/Ferlab-Ste-Justine/terracd/terraform"
+)
+
+const (
+	PendingPlanObject        = "pending-plan"
+	PendingPlanSummaryObject = "pending-plan-summary.json"
+	ApprovalObject           = "approval.yml"
+)
+
+type ApprovalResult struct {
+	Applied          bool
+	AwaitingApproval bool
+	PendingPlan      state.PendingPlan
+	PlanSummary      *terraform.PlanSummary
+}
+
+func clearPendingPlan(store state.StateStore) error {
+	for _, obj := range []string{PendingPlanObject, PendingPlanSummaryObject, ApprovalObject} {
+		delErr := store.DeleteObject(obj)
+		if delErr != nil {
+			return delErr
+		}
+	}
+
+	return nil
+}
+
+func getApproval(store state.StateStore) (state.Approval, bool, error) {
+	var approval state.Approval
+
+	data, exists, readErr := store.ReadObject(ApprovalObject)
+	if readErr != nil || !exists {
+		return approval, false, readErr
+	}
+
+	err := yaml.Unmarshal(data, &approval)
+	if err != nil {
+		return approval, false, errors.New(fmt.Sprintf("Error parsing the plan approval: %s", err.Error()))
+	}
+
+	return approval, true, nil
+}
+
+func getPendingPlanSummary(store state.StateStore) (*terraform.PlanSummary, error) {
+	data, exists, readErr := store.ReadObject(PendingPlanSummaryObject)
+	if readErr != nil || !exists {
+		return nil, readErr
+	}
+
+	var summary terraform.PlanSummary
+	err := json.Unmarshal(data, &summary)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Error parsing the pending plan summary: %s", err.Error()))
+	}
+
+	return &summary, nil
+}
+
+func applyApprovedPlan(ctx context.Context, dir string, conf config.Config, store state.StateStore, pending state.PendingPlan) error {
+	planName := "terracd-plan"
+
+	planData, exists, readErr := store.ReadObject(PendingPlanObject)
+	if readErr != nil {
+		return readErr
+	}
+	if !exists {
+		return errors.New(fmt.Sprintf("Approved plan %s could not be found in the state store", pending.Hash))
+	}
+
+	if fmt.Sprintf("%x", sha256.Sum256(planData)) != pending.Hash {
+		return errors.New(fmt.Sprintf("Plan retrieved from the state store does not match the approved plan hash %s", pending.Hash))
+	}
+
+	initErr := terraform.Init(ctx, dir, conf.TerraformPath, conf.Timeouts.TerraformInit)
+	if initErr != nil {
+		return initErr
+	}
+
+	writeErr := ioutil.WriteFile(path.Join(dir, planName), planData, 0600)
+	if writeErr != nil {
+		return errors.New(fmt.Sprintf("Error restoring the approved plan file: %s", writeErr.Error()))
+	}
+
+	return terraform.Apply(ctx, dir, planName, conf.TerraformPath, conf.Timeouts.TerraformApply)
+}
+
+func savePendingPlan(dir string, store state.StateStore, summary *terraform.PlanSummary, sources recurrence.Occurrence) (state.PendingPlan, error) {
+	planName := "terracd-plan"
+
+	planData, readErr := ioutil.ReadFile(path.Join(dir, planName))
+	if readErr != nil {
+		return state.PendingPlan{}, errors.New(fmt.Sprintf("Error reading the plan file: %s", readErr.Error()))
+	}
+
+	summaryData, marErr := json.Marshal(summary)
+	if marErr != nil {
+		return state.PendingPlan{}, errors.New(fmt.Sprintf("Error serializing the plan summary: %s", marErr.Error()))
+	}
+
+	writeErr := store.DeleteObject(ApprovalObject)
+	if writeErr != nil {
+		return state.PendingPlan{}, writeErr
+	}
+
+	writeErr = store.WriteObject(PendingPlanObject, planData)
+	if writeErr != nil {
+		return state.PendingPlan{}, writeErr
+	}
+
+	writeErr = store.WriteObject(PendingPlanSummaryObject, summaryData)
+	if writeErr != nil {
+		return state.PendingPlan{}, writeErr
+	}
+
+	return state.PendingPlan{
+		Hash: fmt.Sprintf("%x", sha256.Sum256(planData)),
+		CommitHashes: sources.CommitHashes,
+		Fingerprint: sources.Fingerprint,
+		Targets: sources.Targets,
+		Replace: sources.Replace,
+		Timestamp: time.Now(),
+	}, nil
+}
+
+func ApplyWithApproval(ctx context.Context, dir string, conf config.Config, store state.StateStore, pending state.PendingPlan, sources recurrence.Occurrence) (ApprovalResult, error) {
+	if pending.IsDefined() {
+		_, planExists, planExistsErr := store.ReadObject(PendingPlanObject)
+		if planExistsErr != nil {
+			return ApprovalResult{PendingPlan: pending}, planExistsErr
+		}
+
+		pendingSources := pending.GetOccurrence()
+		sourcesChanged := pendingSources.SourcesChanged(&sources)
+		optionsChanged := pendingSources.OptionsChanged(&sources)
+		if sourcesChanged || optionsChanged || !planExists {
+			if sourcesChanged {
+				fmt.Printf("Info: Sources changed since plan %s was produced. Discarding it and any approval it received.\n", pending.Hash)
+			} else if optionsChanged {
+				fmt.Printf("Info: Targets or replaced resources changed since plan %s was produced. Discarding it and any approval it received.\n", pending.Hash)
+			} else {
+				fmt.Printf("Warning: Plan %s could not be found in the state store. Discarding it.\n", pending.Hash)
+			}
+
+			clearErr := clearPendingPlan(store)
+			if clearErr != nil {
+				return ApprovalResult{PendingPlan: pending}, clearErr
+			}
+		} else {
+			summary, summaryErr := getPendingPlanSummary(store)
+			if summaryErr != nil {
+				return ApprovalResult{PendingPlan: pending}, summaryErr
+			}
+
+			approval, approved, approvalErr := getApproval(store)
+			if approvalErr != nil {
+				return ApprovalResult{PendingPlan: pending, PlanSummary: summary}, approvalErr
+			}
+
+			if !approved || approval.PlanHash != pending.Hash {
+				if approved {
+					fmt.Printf("Warning: Approval found for plan %s which does not match the pending plan. Ignoring it.\n", approval.PlanHash)
+				}
+				fmt.Printf("Info: Plan %s is awaiting approval. Skipped apply.\n", pending.Hash)
+				return ApprovalResult{AwaitingApproval: true, PendingPlan: pending, PlanSummary: summary}, nil
+			}
+
+			fmt.Printf("Info: Plan %s was approved. Applying it.\n", pending.Hash)
+			applyErr := applyApprovedPlan(ctx, dir, conf, store, pending)
+			if applyErr != nil {
+				fmt.Printf("Warning: Applying plan %s failed. Discarding it so that a new plan is produced on the next execution.\n", pending.Hash)
+				clearErr := clearPendingPlan(store)
+				if clearErr != nil {
+					fmt.Printf("Warning: Failed to discard plan %s: %s\n", pending.Hash, clearErr.Error())
+					return ApprovalResult{PendingPlan: pending, PlanSummary: summary}, applyErr
+				}
+
+				return ApprovalResult{PlanSummary: summary}, applyErr
+			}
+
+			clearErr := clearPendingPlan(store)
+			if clearErr != nil {
+				return ApprovalResult{Applied: true, PlanSummary: summary}, clearErr
+			}
+
+			return ApprovalResult{Applied: true, PlanSummary: summary}, nil
+		}
+	}
+
+	changes, summary, planErr := Plan(ctx, dir, conf)
+	if planErr != nil {
+		return ApprovalResult{PlanSummary: summary}, planErr
+	}
+
+	if !changes {
+		return ApprovalResult{PlanSummary: summary}, nil
+	}
+
+	newPending, saveErr := savePendingPlan(dir, store, summary, sources)
+	if saveErr != nil {
+		return ApprovalResult{PlanSummary: summary}, saveErr
+	}
+
+	fmt.Printf("Info: Plan %s was saved in the state store and is awaiting approval. Approve it by writing \"plan_hash: %s\" in the %s object of the state store.\n", newPending.Hash, newPending.Hash, ApprovalObject)
+	return ApprovalResult{AwaitingApproval: true, PendingPlan: newPending, PlanSummary: summary}, nil
+}
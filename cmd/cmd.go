@@ -1,16 +1,19 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"path"
+	"strings"
 
 	"github.com/Ferlab-Ste-Justine/terracd/config"
 	"github.com/Ferlab-Ste-Justine/terracd/fs"
 	"github.com/Ferlab-Ste-Justine/terracd/terraform"
 )
 
-func MigrateBackend(dir string, conf config.Config) error {
-	initErr := terraform.Init(dir, conf.TerraformPath, conf.Timeouts.TerraformInit)
+func MigrateBackend(ctx context.Context, dir string, conf config.Config) error {
+	initErr := terraform.Init(ctx, dir, conf.TerraformPath, conf.Timeouts.TerraformInit)
 	if initErr != nil {
 		return initErr
 	}
@@ -26,7 +29,7 @@ func MigrateBackend(dir string, conf config.Config) error {
 		return copyErr
 	}
 
-	initErr = terraform.Init(dir, conf.TerraformPath, conf.Timeouts.TerraformInit)
+	initErr = terraform.Init(ctx, dir, conf.TerraformPath, conf.Timeouts.TerraformInit)
 	if initErr != nil {
 		return initErr
 	}
@@ -34,62 +37,74 @@ func MigrateBackend(dir string, conf config.Config) error {
 	return nil
 }
 
-func Plan(dir string, conf config.Config) (bool, error) {
+const forbiddenOpsFsPattern = "*.terracd-fo.yml"
+
+func Plan(ctx context.Context, dir string, conf config.Config) (bool, *terraform.PlanSummary, error) {
 	planName := "terracd-plan"
-	forbiddenOpsFsPattern := "*.terracd-fo.yml"
 
-	initErr := terraform.Init(dir, conf.TerraformPath, conf.Timeouts.TerraformInit)
+	initErr := terraform.Init(ctx, dir, conf.TerraformPath, conf.Timeouts.TerraformInit)
 	if initErr != nil {
-		return false, initErr
+		return false, nil, initErr
+	}
+
+	if len(conf.Targets) > 0 || len(conf.Replace) > 0 {
+		fmt.Printf("Warning: Planning with targets [%s] and replaced resources [%s]. The plan may not include all the changes of the sources.\n", strings.Join(conf.Targets, ", "), strings.Join(conf.Replace, ", "))
 	}
 
-	changes, planErr := terraform.Plan(dir, planName, conf.TerraformPath, conf.Timeouts.TerraformPlan)
+	changes, planErr := terraform.Plan(ctx, dir, planName, conf.TerraformPath, conf.Timeouts.TerraformPlan, terraform.PlanOptions{
+		Targets: conf.Targets,
+		Replace: conf.Replace,
+	})
 	if planErr != nil {
-		return false, planErr
+		return false, nil, planErr
 	}
 
 	if !changes {
-		return false, nil
+		return false, &terraform.PlanSummary{Changes: []terraform.ResourceChangeSummary{}}, nil
+	}
+
+	plan, showErr := terraform.ShowPlan(ctx, dir, planName, conf.TerraformPath)
+	if showErr != nil {
+		return true, nil, showErr
 	}
 
+	summary := terraform.SummarizePlan(plan)
+	summary.Print()
+
 	forbiddenOpsFiles, foFilesErr := fs.FindFiles(dir, forbiddenOpsFsPattern)
 	if foFilesErr != nil {
-		return true, foFilesErr
+		return true, &summary, foFilesErr
 	}
 
 	forbiddenOps, foErr := terraform.GetForbiddenOperations(forbiddenOpsFiles)
 	if foErr != nil {
-		return true, foErr
+		return true, &summary, foErr
+	}
+
+	checkErr := terraform.CheckPlan(plan, forbiddenOps)
+	if checkErr != nil {
+		return true, &summary, checkErr
+	}
+
+	changeBudget, budgetErr := terraform.GetChangeBudget(forbiddenOpsFiles)
+	if budgetErr != nil {
+		return true, &summary, budgetErr
 	}
 
-	return true, terraform.CheckPlan(dir, planName, conf.TerraformPath, forbiddenOps)
+	return true, &summary, terraform.CheckChangeBudget(plan, summary, conf.ChangeBudget.Merge(changeBudget))
 }
 
-func Apply(dir string, conf config.Config) (bool, error) {
+func Apply(ctx context.Context, dir string, conf config.Config) (bool, *terraform.PlanSummary, error) {
 	planName := "terracd-plan"
 
-	changes, planErr := Plan(dir, conf)
+	changes, summary, planErr := Plan(ctx, dir, conf)
 	if planErr != nil {
-		return changes, planErr
+		return changes, summary, planErr
 	}
 
 	if !changes {
-		return false, nil
+		return false, summary, nil
 	}
 
-	return true, terraform.Apply(dir, planName, conf.TerraformPath, conf.Timeouts.TerraformApply)
+	return true, summary, terraform.Apply(ctx, dir, planName, conf.TerraformPath, conf.Timeouts.TerraformApply)
 }
-
-func Destroy(dir string, conf config.Config) error {
-	initErr := terraform.Init(dir, conf.TerraformPath, conf.Timeouts.TerraformInit)
-	if initErr != nil {
-		return initErr
-	}
-
-	destroyErr := terraform.Destroy(dir, conf.TerraformPath, conf.Timeouts.TerraformDestroy)
-	if destroyErr != nil {
-		return destroyErr
-	}
-
-	return nil
-}
\ No newline at end of file
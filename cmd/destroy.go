@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Ferlab-Ste-Justine/terracd/config"
+	"github.com/Ferlab-Ste-Justine/terracd/fs"
+	"github.com/Ferlab-Ste-Justine/terracd/state"
+	"github.com/Ferlab-Ste-Justine/terracd/terraform"
+)
+
+const (
+	DestroyConfirmationObject = "destroy-confirmation"
+	destroyMarkerFsPattern    = "*.terracd-destroy"
+)
+
+//Returns an error unless the destroy was confirmed, either by a marker file in the sources or by a confirmation token in the configuration matching the one in the state store
+func CheckDestroyConfirmation(dir string, conf config.Config, store state.StateStore) error {
+	markers, markersErr := fs.FindFiles(dir, destroyMarkerFsPattern)
+	if markersErr != nil {
+		return markersErr
+	}
+
+	if len(markers) > 0 {
+		fmt.Printf("Info: Destroy confirmed by marker file %s.\n", markers[0])
+		return nil
+	}
+
+	if conf.Destroy.Confirmation == "" {
+		return errors.New(fmt.Sprintf("Destroy was not confirmed. Add a file matching the %s pattern to the sources or set the destroy confirmation in the configuration and write the same value in the %s object of the state store.", destroyMarkerFsPattern, DestroyConfirmationObject))
+	}
+
+	token, exists, readErr := store.ReadObject(DestroyConfirmationObject)
+	if readErr != nil {
+		return errors.New(fmt.Sprintf("Error reading the destroy confirmation: %s", readErr.Error()))
+	}
+
+	if !exists {
+		return errors.New(fmt.Sprintf("Destroy was not confirmed. Write the destroy confirmation of the configuration in the %s object of the state store.", DestroyConfirmationObject))
+	}
+
+	if strings.TrimSpace(string(token)) != conf.Destroy.Confirmation {
+		return errors.New(fmt.Sprintf("Destroy was not confirmed. The value of the %s object of the state store does not match the destroy confirmation of the configuration.", DestroyConfirmationObject))
+	}
+
+	fmt.Println("Info: Destroy confirmed by the confirmation in the state store.")
+	return nil
+}
+
+//Generates a destroy plan, checks it against the forbidden operations and applies it
+func Destroy(ctx context.Context, dir string, conf config.Config) (bool, *terraform.PlanSummary, error) {
+	planName := "terracd-destroy-plan"
+
+	initErr := terraform.Init(ctx, dir, conf.TerraformPath, conf.Timeouts.TerraformInit)
+	if initErr != nil {
+		return false, nil, initErr
+	}
+
+	if len(conf.Targets) > 0 {
+		fmt.Printf("Warning: Destroying only the targets [%s].\n", strings.Join(conf.Targets, ", "))
+	}
+
+	changes, planErr := terraform.Plan(ctx, dir, planName, conf.TerraformPath, conf.Timeouts.TerraformPlan, terraform.PlanOptions{
+		Destroy: true,
+		Targets: conf.Targets,
+	})
+	if planErr != nil {
+		return false, nil, planErr
+	}
+
+	if !changes {
+		fmt.Println("Info: Destroy plan indicated no operations. Skipped destroy.")
+		return false, &terraform.PlanSummary{Changes: []terraform.ResourceChangeSummary{}}, nil
+	}
+
+	plan, showErr := terraform.ShowPlan(ctx, dir, planName, conf.TerraformPath)
+	if showErr != nil {
+		return true, nil, showErr
+	}
+
+	summary := terraform.SummarizePlan(plan)
+	summary.Print()
+
+	forbiddenOpsFiles, foFilesErr := fs.FindFiles(dir, forbiddenOpsFsPattern)
+	if foFilesErr != nil {
+		return true, &summary, foFilesErr
+	}
+
+	forbiddenOps, foErr := terraform.GetForbiddenOperations(forbiddenOpsFiles)
+	if foErr != nil {
+		return true, &summary, foErr
+	}
+
+	checkErr := terraform.CheckPlan(plan, forbiddenOps)
+	if checkErr != nil {
+		return true, &summary, checkErr
+	}
+
+	return true, &summary, terraform.Apply(ctx, dir, planName, conf.TerraformPath, conf.Timeouts.TerraformDestroy)
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Ferlab-Ste-Justine/terracd/config"
+	"github.com/Ferlab-Ste-Justine/terracd/terraform"
+)
+
+type DriftResult int64
+
+const (
+	DriftUndefined DriftResult = iota
+	DriftInSync
+	DriftCodeChanges
+	DriftOutOfBand
+)
+
+func (result DriftResult) ToString() string {
+	switch result {
+	case DriftInSync:
+		return "in_sync"
+	case DriftCodeChanges:
+		return "code_changes_pending"
+	case DriftOutOfBand:
+		return "out_of_band_drift"
+	default:
+		return ""
+	}
+}
+
+func (result DriftResult) Describe() string {
+	switch result {
+	case DriftInSync:
+		return "in sync"
+	case DriftCodeChanges:
+		return "code changes pending"
+	case DriftOutOfBand:
+		return "out-of-band drift"
+	default:
+		return "undefined"
+	}
+}
+
+func Drift(ctx context.Context, dir string, conf config.Config) (DriftResult, *terraform.PlanSummary, error) {
+	refreshPlanName := "terracd-refresh-plan"
+	planName := "terracd-plan"
+
+	initErr := terraform.Init(ctx, dir, conf.TerraformPath, conf.Timeouts.TerraformInit)
+	if initErr != nil {
+		return DriftUndefined, nil, initErr
+	}
+
+	_, refreshErr := terraform.Plan(ctx, dir, refreshPlanName, conf.TerraformPath, conf.Timeouts.TerraformPlan, terraform.PlanOptions{RefreshOnly: true})
+	if refreshErr != nil {
+		return DriftUndefined, nil, refreshErr
+	}
+
+	refreshPlan, showErr := terraform.ShowPlan(ctx, dir, refreshPlanName, conf.TerraformPath)
+	if showErr != nil {
+		return DriftUndefined, nil, showErr
+	}
+
+	//Refresh plans also report changes to outputs, which come from the code and not from the resources
+	outOfBand := false
+	for _, drift := range refreshPlan.ResourceDrift {
+		if drift.Change == nil || drift.Change.Actions.NoOp() || drift.Change.Actions.Read() {
+			continue
+		}
+
+		fmt.Printf("Info: Resource \"%s\" was changed outside of terraform.\n", drift.Address)
+		outOfBand = true
+	}
+
+	changes, planErr := terraform.Plan(ctx, dir, planName, conf.TerraformPath, conf.Timeouts.TerraformPlan, terraform.PlanOptions{})
+	if planErr != nil {
+		return DriftUndefined, nil, planErr
+	}
+
+	summary := terraform.PlanSummary{Changes: []terraform.ResourceChangeSummary{}}
+	if changes {
+		plan, planShowErr := terraform.ShowPlan(ctx, dir, planName, conf.TerraformPath)
+		if planShowErr != nil {
+			return DriftUndefined, nil, planShowErr
+		}
+
+		summary = terraform.SummarizePlan(plan)
+		summary.Print()
+	}
+
+	if outOfBand {
+		return DriftOutOfBand, &summary, nil
+	}
+
+	if changes {
+		return DriftCodeChanges, &summary, nil
+	}
+
+	return DriftInSync, &summary, nil
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path"
+
+	"github.com/Ferlab-Ste-Justine/terracd/config"
+	"github.com/Ferlab-Ste-Justine/terracd/fs"
+	"github.com/Ferlab-Ste-Justine/terracd/state"
+	"github.com/Ferlab-Ste-Justine/terracd/terraform"
+)
+
+const OutputsObject = "outputs.json"
+
+func countSensitiveOutputs(outputs terraform.Outputs) int {
+	count := 0
+	for _, output := range outputs {
+		if output.Sensitive {
+			count += 1
+		}
+	}
+
+	return count
+}
+
+func ExportOutputs(ctx context.Context, dir string, conf config.Config, store state.StateStore) error {
+	outputs, outputErr := terraform.Output(ctx, dir, conf.TerraformPath, conf.Timeouts.TerraformOutput)
+	if outputErr != nil {
+		return outputErr
+	}
+
+	data, marErr := json.Marshal(outputs)
+	if marErr != nil {
+		return errors.New(fmt.Sprintf("Error serializing the terraform outputs: %s", marErr.Error()))
+	}
+
+	writeErr := store.WriteObject(OutputsObject, data)
+	if writeErr != nil {
+		return writeErr
+	}
+
+	fmt.Printf("Info: Exported %d outputs (%d sensitive) to the state store.\n", len(outputs), countSensitiveOutputs(outputs))
+	return nil
+}
+
+func GenerateOutputsFiles(imports []config.OutputsImport, generatedDir string) error {
+	for _, imp := range imports {
+		paths := fs.GetPaths(imp.DataPath, imp.DataPath)
+		data, exists, readErr := state.ReadStoreObject(imp.StateStore, paths, OutputsObject)
+		if readErr != nil {
+			return errors.New(fmt.Sprintf("Error reading the outputs of stack \"%s\": %s", imp.Stack, readErr.Error()))
+		}
+
+		if !exists {
+			return errors.New(fmt.Sprintf("No outputs were found for stack \"%s\". Make sure it exports them and was applied.", imp.Stack))
+		}
+
+		var outputs terraform.Outputs
+		unmarErr := json.Unmarshal(data, &outputs)
+		if unmarErr != nil {
+			return errors.New(fmt.Sprintf("Error parsing the outputs of stack \"%s\": %s", imp.Stack, unmarErr.Error()))
+		}
+
+		values := map[string]json.RawMessage{}
+		for name, output := range outputs {
+			values[name] = output.Value
+		}
+
+		content, marErr := json.MarshalIndent(map[string]interface{}{imp.GetVariable(): values}, "", "  ")
+		if marErr != nil {
+			return errors.New(fmt.Sprintf("Error serializing the outputs of stack \"%s\": %s", imp.Stack, marErr.Error()))
+		}
+
+		fileName := fmt.Sprintf("%s.auto.tfvars.json", imp.GetVariable())
+		writeErr := ioutil.WriteFile(path.Join(generatedDir, fileName), content, 0600)
+		if writeErr != nil {
+			return errors.New(fmt.Sprintf("Error writing the outputs of stack \"%s\": %s", imp.Stack, writeErr.Error()))
+		}
+
+		fmt.Printf("Info: Imported %d outputs (%d sensitive) of stack %s in variable %s.\n", len(outputs), countSensitiveOutputs(outputs), imp.Stack, imp.GetVariable())
+	}
+
+	return nil
+}
@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path"
@@ -11,7 +13,9 @@ import (
 	"github.com/Ferlab-Ste-Justine/terracd/jitter"
 	"github.com/Ferlab-Ste-Justine/terracd/metrics"
 	"github.com/Ferlab-Ste-Justine/terracd/recurrence"
+	"github.com/Ferlab-Ste-Justine/terracd/source"
 	"github.com/Ferlab-Ste-Justine/terracd/state"
+	"github.com/Ferlab-Ste-Justine/terracd/terraform"
 )
 
 func backupFsState(workDir string, stateDir string) error {
@@ -47,6 +51,23 @@ func backupFsState(workDir string, stateDir string) error {
 	return nil
 }
 
+func sleep(ctx context.Context, duration time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(duration):
+		return nil
+	}
+}
+
+func savePlanSummary(summary *terraform.PlanSummary, summaryPath string) error {
+	if summary == nil {
+		return nil
+	}
+
+	return summary.Save(summaryPath)
+}
+
 func cleanup(workDir string, stateDir string) error {
 	backupErr := backupFsState(workDir, stateDir)
 	if backupErr != nil {
@@ -56,60 +77,94 @@ func cleanup(workDir string, stateDir string) error {
 	return os.RemoveAll(workDir)
 }
 
-func RunConfig(paths fs.Paths, conf config.Config, st state.State) (state.State, bool, []metrics.Provider, error) {
+type RunInfo struct {
+	Skipped             bool
+	SkipReason          string
+	CommitHashes        []source.CommitHash
+	Fingerprint         string
+	Providers           []metrics.Provider
+	Drift               DriftResult
+	PlanSummary         *terraform.PlanSummary
+	ForbiddenOperations []terraform.ForbiddenOperationViolation
+	PendingPlanHash     string
+}
+
+func getFailedPlanInfo(summary *terraform.PlanSummary, err error) RunInfo {
+	info := RunInfo{PlanSummary: summary}
+
+	var forOpsErr *terraform.ForbiddenOperationsError
+	if errors.As(err, &forOpsErr) {
+		info.ForbiddenOperations = forOpsErr.Violations
+	}
+
+	return info
+}
+
+//Executes the command of the configuration and returns the resulting state.
+//On error, the returned state still reflects the changes already made to the state store, like a pending plan that was saved or discarded.
+func RunConfig(ctx context.Context, paths fs.Paths, conf config.Config, st state.State, store state.StateStore) (state.State, RunInfo, error) {
+	sources := recurrence.Occurrence{CommitHashes: []source.CommitHash{}}
+	newSt, info, err := runConfig(ctx, paths, conf, st, store, &sources)
+	info.CommitHashes = sources.CommitHashes
+	info.Fingerprint = sources.Fingerprint
+	return newSt, info, err
+}
+
+//Executes the command, reporting the commit hashes and fingerprint of the sources as soon as they are known so that they are available even if it fails afterwards
+func runConfig(ctx context.Context, paths fs.Paths, conf config.Config, st state.State, store state.StateStore, sources *recurrence.Occurrence) (state.State, RunInfo, error) {
 	fmt.Printf("Info: Running %s command.\n", conf.Command)
 	
 	workDirExists, workDirExistsErr := fs.PathExists(paths.Root)
 	if workDirExistsErr != nil {
-		return st, false, []metrics.Provider{}, workDirExistsErr
+		return st, RunInfo{}, workDirExistsErr
 	}
 	if !workDirExists {
 		assureErr := fs.AssurePrivateDir(paths.Root)
 		if assureErr != nil {
-			return st, false, []metrics.Provider{}, assureErr
+			return st, RunInfo{}, assureErr
 		}
 	}
 
 	workDirExists, workDirExistsErr = fs.PathExists(paths.Work)
 	if workDirExistsErr != nil {
-		return st, false, []metrics.Provider{}, workDirExistsErr
+		return st, RunInfo{}, workDirExistsErr
 	}
 	if workDirExists {
 		fmt.Println("Warning: Working directory found from prior iteration. Will clean it up.")
 		cleanupErr := cleanup(paths.Work, paths.TfState)
 		if cleanupErr != nil {
-			return st, false, []metrics.Provider{}, cleanupErr
+			return st, RunInfo{}, cleanupErr
 		}
 	}
 
 	assureErr := fs.AssurePrivateDir(paths.Repos)
 	if assureErr != nil {
-		return st, false, []metrics.Provider{}, assureErr
+		return st, RunInfo{}, assureErr
 	}
 
 	assureErr = fs.AssurePrivateDir(paths.Backend)
 	if assureErr != nil {
-		return st, false, []metrics.Provider{}, assureErr
+		return st, RunInfo{}, assureErr
 	}
 
 	assureErr = fs.AssurePrivateDir(paths.TfState)
 	if assureErr != nil {
-		return st, false, []metrics.Provider{}, assureErr
+		return st, RunInfo{}, assureErr
 	}
 
 	assureErr = fs.AssurePrivateDir(paths.ProviderCache)
 	if assureErr != nil {
-		return st, false, []metrics.Provider{}, assureErr
+		return st, RunInfo{}, assureErr
 	}
 
 	assureErr = fs.AssurePrivateDir(paths.Work)
 	if assureErr != nil {
-		return st, false, []metrics.Provider{}, assureErr
+		return st, RunInfo{}, assureErr
 	}
 
 	gitCacheLoadErr := conf.Cache.GitSources.Load(paths.Repos)
 	if gitCacheLoadErr != nil {
-		return st, false, []metrics.Provider{}, gitCacheLoadErr
+		return st, RunInfo{}, gitCacheLoadErr
 	}
 	
 	defer func() {
@@ -128,31 +183,87 @@ func RunConfig(paths fs.Paths, conf config.Config, st state.State) (state.State,
 
 	commitHashes, syncErr := conf.Sources.SyncGitRepos(paths.Repos)
 	if syncErr != nil {
-		return st, false, []metrics.Provider{}, syncErr
+		return st, RunInfo{}, syncErr
 	}
+	sources.CommitHashes = commitHashes
 
-	cmdOcc := recurrence.GenerateCommandOccurrence(conf.Command, commitHashes)
-	if conf.Recurrence.IsDefined() {
-		if !st.LastCommandOccurrence.ShouldOccur(&conf.Recurrence, cmdOcc) {
-			fmt.Println("Info: Recurrence policy dictates that execution should be skipped at this time.")
-			return st, true, []metrics.Provider{}, nil
+	backendGenErr := conf.Sources.GenerateBackendFiles(paths.Backend)
+	if backendGenErr != nil {
+		return st, RunInfo{}, backendGenErr
+	}
+
+	removeErr := os.RemoveAll(paths.Generated)
+	if removeErr != nil {
+		return st, RunInfo{}, removeErr
+	}
+
+	assureErr = fs.AssurePrivateDir(paths.Generated)
+	if assureErr != nil {
+		return st, RunInfo{}, assureErr
+	}
+
+	defer func() {
+		removeErr := os.RemoveAll(paths.Generated)
+		if removeErr != nil {
+			fmt.Printf("Warning: Failed to cleanup generated files at the end of execution: %s.\n", removeErr.Error())
 		}
+	}()
+
+	outputsGenErr := GenerateOutputsFiles(conf.ImportOutputs, paths.Generated)
+	if outputsGenErr != nil {
+		return st, RunInfo{}, outputsGenErr
 	}
 
-	backendGenErr := conf.Sources.GenerateBackendFiles(paths.Backend)
-	if backendGenErr != nil {
-		return st, false, []metrics.Provider{}, backendGenErr
+	varsGenErr := GenerateVariablesFile(conf, paths.Generated)
+	if varsGenErr != nil {
+		return st, RunInfo{}, varsGenErr
+	}
+
+	fingerprint, fingerprintErr := fs.GetDirsSha256(append(conf.Sources.GetFsPaths(paths.Repos), paths.Backend, paths.Generated))
+	if fingerprintErr != nil {
+		return st, RunInfo{}, fingerprintErr
+	}
+	sources.Fingerprint = fingerprint
+
+	triggerValue := ""
+	if conf.Trigger.IsDefined() {
+		value, triggerErr := conf.Trigger.Read()
+		if triggerErr != nil {
+			return st, RunInfo{}, triggerErr
+		}
+		triggerValue = value
+		if triggerValue != "" && triggerValue != st.LastCommandOccurrence.Occurrence.Trigger {
+			fmt.Println("Info: The value of the trigger changed since the last execution.")
+		}
 	}
 
-	mergeDirs := append(conf.Sources.GetFsPaths(paths.Repos), paths.TfState, paths.Backend)
+	cmdOcc := recurrence.GenerateCommandOccurrence(conf.Command, recurrence.Occurrence{
+		CommitHashes: commitHashes,
+		Fingerprint: fingerprint,
+		Trigger: triggerValue,
+		Targets: conf.Targets,
+		Replace: conf.Replace,
+	})
+	if conf.Recurrence.IsDefined() {
+		shouldOccur, skipReason := st.LastCommandOccurrence.ShouldOccur(&conf.Recurrence, cmdOcc, &st.Failures)
+		if !shouldOccur {
+			if skipReason == recurrence.SkipMaxFailures || skipReason == recurrence.SkipRetryBackoff {
+				fmt.Printf("Info: The last %d attempts of the %s command with the current sources failed, most recently at %s.\n", st.Failures.Count, st.Failures.Command, st.Failures.Timestamp.Format(time.RFC3339))
+			}
+			fmt.Printf("Info: Recurrence policy dictates that execution should be skipped at this time (reason: %s).\n", skipReason)
+			return st, RunInfo{Skipped: true, SkipReason: skipReason}, nil
+		}
+	}
+
+	mergeDirs := append(conf.Sources.GetFsPaths(paths.Repos), paths.TfState, paths.Backend, paths.Generated)
 	mergeErr := fs.MergeDirs(paths.Work, mergeDirs)
 	if mergeErr != nil {
-		return st, false, []metrics.Provider{}, mergeErr
+		return st, RunInfo{}, mergeErr
 	}
 
 	cacheInfo, cacheDirInfo, cacheInfoErr := conf.Cache.Providers.Load(paths.Work, paths.ProviderCache, st.CacheInfo)
 	if cacheInfoErr != nil {
-		return st, false, []metrics.Provider{}, cacheInfoErr
+		return st, RunInfo{}, cacheInfoErr
 	}
 
 	defer func() {
@@ -168,72 +279,143 @@ func RunConfig(paths fs.Paths, conf config.Config, st state.State) (state.State,
 		jitter.Seed()
 		sleepDuration := jitter.GetRandomDuration(conf.RandomJitter)
 		fmt.Printf("Info: Sleeping for %s\n", jitter.Stringify(sleepDuration))
-		time.Sleep(sleepDuration)
-	}
-
-	previousWd, previousWdErr := os.Getwd()
-	if previousWdErr != nil {
-		return st, false, []metrics.Provider{}, previousWdErr
-	}
-
-	chdirErr := os.Chdir(paths.Root)
-	if chdirErr != nil {
-		return st, false, []metrics.Provider{}, chdirErr
-	}
-
-	defer func() {
-		chdirErr := os.Chdir(previousWd)
-		if chdirErr != nil {
-			fmt.Printf("Warning: Failed reverting working directory after running terraform: %s\n", chdirErr.Error())
+		sleepErr := sleep(ctx, sleepDuration)
+		if sleepErr != nil {
+			return st, RunInfo{}, sleepErr
 		}
-	}()
+	}
 
+	info := RunInfo{}
+	pendingPlan := st.PendingPlan
 	switch conf.Command {
 	case "wait":
 		waitTime := conf.Timeouts.Wait
 		if int64(waitTime) == int64(0) {
 			waitTime, _ = time.ParseDuration("1h")
 		}
-		time.Sleep(waitTime)
+		sleepErr := sleep(ctx, waitTime)
+		if sleepErr != nil {
+			return st, RunInfo{}, sleepErr
+		}
 	case "plan":
-		_, planErr := Plan(paths.Work, conf)
+		_, summary, planErr := Plan(ctx, paths.Work, conf)
+		saveErr := savePlanSummary(summary, paths.PlanSummary)
 		if planErr != nil {
-			return st, false, []metrics.Provider{}, planErr
+			return st, getFailedPlanInfo(summary, planErr), planErr
 		}
+		if saveErr != nil {
+			return st, RunInfo{PlanSummary: summary}, saveErr
+		}
+		info.PlanSummary = summary
 	case "apply":
-		applied, applyErr := Apply(paths.Work, conf)
+		if conf.Approval.Required {
+			result, applyErr := ApplyWithApproval(ctx, paths.Work, conf, store, st.PendingPlan, cmdOcc.Occurrence)
+			pendingPlan = result.PendingPlan
+			//The pending plan objects may have been updated in the state store before the error, so the state must reflect them
+			errSt := st
+			errSt.PendingPlan = pendingPlan
+			saveErr := savePlanSummary(result.PlanSummary, paths.PlanSummary)
+			if applyErr != nil {
+				return errSt, getFailedPlanInfo(result.PlanSummary, applyErr), applyErr
+			}
+			if saveErr != nil {
+				return errSt, RunInfo{PlanSummary: result.PlanSummary}, saveErr
+			}
+			info.PlanSummary = result.PlanSummary
+			info.PendingPlanHash = pendingPlan.Hash
+			info.Skipped = result.AwaitingApproval
+			if info.Skipped {
+				info.SkipReason = "awaiting_approval"
+			}
+			if conf.ExportOutputs && !pendingPlan.IsDefined() {
+				exportErr := ExportOutputs(ctx, paths.Work, conf, store)
+				if exportErr != nil {
+					return errSt, info, exportErr
+				}
+			}
+			break
+		}
+
+		applied, summary, applyErr := Apply(ctx, paths.Work, conf)
+		saveErr := savePlanSummary(summary, paths.PlanSummary)
 		if applyErr != nil {
-			return st, false, []metrics.Provider{}, applyErr
+			return st, getFailedPlanInfo(summary, applyErr), applyErr
 		}
+		if saveErr != nil {
+			return st, RunInfo{PlanSummary: summary}, saveErr
+		}
+		info.PlanSummary = summary
 		if !applied {
 			fmt.Println("Info: Plan indicated no operations. Skipped apply.")
 		}
+		if conf.ExportOutputs {
+			exportErr := ExportOutputs(ctx, paths.Work, conf, store)
+			if exportErr != nil {
+				return st, info, exportErr
+			}
+		}
+	case "drift":
+		drift, summary, driftErr := Drift(ctx, paths.Work, conf)
+		saveErr := savePlanSummary(summary, paths.PlanSummary)
+		if driftErr != nil {
+			return st, RunInfo{PlanSummary: summary}, driftErr
+		}
+		if saveErr != nil {
+			return st, RunInfo{PlanSummary: summary}, saveErr
+		}
+		info.Drift = drift
+		info.PlanSummary = summary
+		fmt.Printf("Info: Drift detection indicates the stack is %s.\n", drift.Describe())
 	case "destroy":
-		destroyErr := Destroy(paths.Work, conf)
+		confirmErr := CheckDestroyConfirmation(paths.Work, conf, store)
+		if confirmErr != nil {
+			return st, RunInfo{}, confirmErr
+		}
+
+		_, summary, destroyErr := Destroy(ctx, paths.Work, conf)
+		saveErr := savePlanSummary(summary, paths.PlanSummary)
 		if destroyErr != nil {
-			return st, false, []metrics.Provider{}, destroyErr
+			return st, getFailedPlanInfo(summary, destroyErr), destroyErr
 		}
+		if saveErr != nil {
+			return st, RunInfo{PlanSummary: summary}, saveErr
+		}
+		info.PlanSummary = summary
+		if conf.ExportOutputs {
+			deleteErr := store.DeleteObject(OutputsObject)
+			if deleteErr != nil {
+				return st, info, deleteErr
+			}
+		}
+	case "import", "state_mv", "state_rm":
+		summary, opsErr := RunStateOperations(ctx, paths.Work, conf, store)
+		saveErr := savePlanSummary(summary, paths.PlanSummary)
+		if opsErr != nil {
+			return st, getFailedPlanInfo(summary, opsErr), opsErr
+		}
+		if saveErr != nil {
+			return st, RunInfo{PlanSummary: summary}, saveErr
+		}
+		info.PlanSummary = summary
 	case "migrate_backend":
-		migrateErr := MigrateBackend(paths.Work, conf)
+		migrateErr := MigrateBackend(ctx, paths.Work, conf)
 		if migrateErr != nil {
-			return st, false, []metrics.Provider{}, migrateErr
+			return st, RunInfo{}, migrateErr
 		}
 	}
 
+	newSt := st
+	newSt.LastCommandOccurrence = *cmdOcc
+	newSt.CacheInfo = cacheInfo
+	newSt.PendingPlan = pendingPlan
+
 	var usedProvidersErr error
-	usedProviders := []metrics.Provider{}
 	if conf.Command != "wait" && conf.Metrics.IncludeProviders {
-		usedProviders, usedProvidersErr = metrics.GetProvidersInfo(paths.Work)
+		info.Providers, usedProvidersErr = metrics.GetProvidersInfo(paths.Work)
 		if usedProvidersErr != nil {
-			return state.State{
-				LastCommandOccurrence: *cmdOcc,
-				CacheInfo: cacheInfo,
-			}, false, usedProviders, usedProvidersErr
+			return newSt, info, usedProvidersErr
 		}
 	}
 
-	return state.State{
-		LastCommandOccurrence: *cmdOcc,
-		CacheInfo: cacheInfo,
-	}, false, usedProviders, nil
+	return newSt, info, nil
 }
\ No newline at end of file
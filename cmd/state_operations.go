@@ -0,0 +1,277 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/Ferlab-Ste-Justine/terracd/config"
+	"github.com/Ferlab-Ste-Justine/terracd/fs"
+	"github.com/Ferlab-Ste-Justine/terracd/state"
+	"github.com/Ferlab-Ste-Justine/terracd/terraform"
+)
+
+const (
+	ExecutedOperationsObject = "executed-operations.yml"
+	stateOpsFsPattern        = "*.terracd-ops.yml"
+	configOperationsSource   = "configuration"
+)
+
+//Set of state operations, from the configuration or from a file in the sources, that was executed by a command.
+//Completed is the number of its operations that succeeded, so that a failed execution resumes after them.
+type ExecutedOperations struct {
+	Command   string
+	Source    string
+	Hash      string
+	Completed int
+	Timestamp time.Time
+}
+
+type stateOperation struct {
+	Description string
+	Execute     func() error
+}
+
+type operationsSource struct {
+	Name       string
+	Hash       string
+	Operations terraform.StateOperations
+}
+
+func (src *operationsSource) describe() string {
+	if src.Name == configOperationsSource {
+		return "the configuration"
+	}
+
+	return fmt.Sprintf("file %s", src.Name)
+}
+
+//Hashes the operations of the command only, so that changes to the operations of other commands do not cause them to be executed again
+func getOperationsHash(ops terraform.StateOperations, command string) (string, error) {
+	var commandOps interface{}
+	switch command {
+	case "import":
+		commandOps = ops.Imports
+	case "state_mv":
+		commandOps = ops.Moves
+	case "state_rm":
+		commandOps = ops.Removals
+	}
+
+	content, marErr := yaml.Marshal(commandOps)
+	if marErr != nil {
+		return "", errors.New(fmt.Sprintf("Error serializing the state operations: %s", marErr.Error()))
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(content)), nil
+}
+
+func getExecutedOperations(store state.StateStore) ([]ExecutedOperations, error) {
+	executed := []ExecutedOperations{}
+
+	data, exists, readErr := store.ReadObject(ExecutedOperationsObject)
+	if readErr != nil {
+		return executed, errors.New(fmt.Sprintf("Error reading the executed state operations: %s", readErr.Error()))
+	}
+
+	if !exists {
+		return executed, nil
+	}
+
+	unmarErr := yaml.Unmarshal(data, &executed)
+	if unmarErr != nil {
+		return executed, errors.New(fmt.Sprintf("Error deserializing the executed state operations: %s", unmarErr.Error()))
+	}
+
+	return executed, nil
+}
+
+func saveExecutedOperations(store state.StateStore, executed []ExecutedOperations) error {
+	content, marErr := yaml.Marshal(&executed)
+	if marErr != nil {
+		return errors.New(fmt.Sprintf("Error serializing the executed state operations: %s", marErr.Error()))
+	}
+
+	writeErr := store.WriteObject(ExecutedOperationsObject, content)
+	if writeErr != nil {
+		return errors.New(fmt.Sprintf("Error writing the executed state operations: %s", writeErr.Error()))
+	}
+
+	return nil
+}
+
+//Returns the number of operations of the source that were already executed by the command
+func getCompletedOperations(executed []ExecutedOperations, command string, src operationsSource) int {
+	for _, exec := range executed {
+		if exec.Command == command && exec.Source == src.Name && exec.Hash == src.Hash {
+			return exec.Completed
+		}
+	}
+
+	return 0
+}
+
+func setCompletedOperations(executed []ExecutedOperations, command string, src operationsSource, completed int) []ExecutedOperations {
+	record := ExecutedOperations{
+		Command: command,
+		Source: src.Name,
+		Hash: src.Hash,
+		Completed: completed,
+		Timestamp: time.Now(),
+	}
+
+	result := []ExecutedOperations{}
+	for _, exec := range executed {
+		if exec.Command == command && exec.Source == src.Name && exec.Hash == src.Hash {
+			continue
+		}
+		result = append(result, exec)
+	}
+
+	return append(result, record)
+}
+
+//Returns the operations of the configuration followed by those of the operation files in the workspace
+func getOperationsSources(dir string, conf config.Config) ([]operationsSource, error) {
+	sources := []operationsSource{}
+
+	confHash, hashErr := getOperationsHash(conf.StateOperations, conf.Command)
+	if hashErr != nil {
+		return sources, hashErr
+	}
+	sources = append(sources, operationsSource{Name: configOperationsSource, Hash: confHash, Operations: conf.StateOperations})
+
+	opsFiles, opsFilesErr := fs.FindFiles(dir, stateOpsFsPattern)
+	if opsFilesErr != nil {
+		return sources, opsFilesErr
+	}
+
+	for _, opsFile := range opsFiles {
+		ops, readErr := terraform.ReadStateOperationsFile(opsFile)
+		if readErr != nil {
+			return sources, readErr
+		}
+
+		fileHash, hashErr := getOperationsHash(ops, conf.Command)
+		if hashErr != nil {
+			return sources, hashErr
+		}
+
+		name, relErr := filepath.Rel(dir, opsFile)
+		if relErr != nil {
+			return sources, relErr
+		}
+
+		sources = append(sources, operationsSource{Name: name, Hash: fileHash, Operations: ops})
+	}
+
+	return sources, nil
+}
+
+//Returns the operations of the command, in the order they should be executed
+func getStateOperations(ctx context.Context, dir string, conf config.Config, ops terraform.StateOperations) []stateOperation {
+	result := []stateOperation{}
+
+	switch conf.Command {
+	case "import":
+		for _, imp := range ops.Imports {
+			imp := imp
+			result = append(result, stateOperation{
+				Description: fmt.Sprintf("Importing \"%s\" with id \"%s\"", imp.Address, imp.Id),
+				Execute: func() error {
+					return terraform.Import(ctx, dir, imp.Address, imp.Id, conf.TerraformPath, conf.Timeouts.TerraformImport)
+				},
+			})
+		}
+	case "state_mv":
+		for _, move := range ops.Moves {
+			move := move
+			result = append(result, stateOperation{
+				Description: fmt.Sprintf("Moving \"%s\" to \"%s\" in the state", move.Source, move.Destination),
+				Execute: func() error {
+					return terraform.StateMv(ctx, dir, move.Source, move.Destination, conf.TerraformPath, conf.Timeouts.TerraformStateMv)
+				},
+			})
+		}
+	case "state_rm":
+		for _, removal := range ops.Removals {
+			removal := removal
+			result = append(result, stateOperation{
+				Description: fmt.Sprintf("Removing \"%s\" from the state", removal.Address),
+				Execute: func() error {
+					return terraform.StateRm(ctx, dir, removal.Address, conf.TerraformPath, conf.Timeouts.TerraformStateRm)
+				},
+			})
+		}
+	}
+
+	return result
+}
+
+//Executes the state operations of the command that were not executed yet, remembering in the state store how many operations of each set were executed
+//after each operation so that an execution interrupted by a failure resumes after the operations that succeeded.
+//If any operation was executed, a plan is then generated to show its effect.
+func RunStateOperations(ctx context.Context, dir string, conf config.Config, store state.StateStore) (*terraform.PlanSummary, error) {
+	initErr := terraform.Init(ctx, dir, conf.TerraformPath, conf.Timeouts.TerraformInit)
+	if initErr != nil {
+		return nil, initErr
+	}
+
+	executed, executedErr := getExecutedOperations(store)
+	if executedErr != nil {
+		return nil, executedErr
+	}
+
+	sources, sourcesErr := getOperationsSources(dir, conf)
+	if sourcesErr != nil {
+		return nil, sourcesErr
+	}
+
+	count := 0
+	for _, src := range sources {
+		ops := getStateOperations(ctx, dir, conf, src.Operations)
+		if len(ops) == 0 {
+			continue
+		}
+
+		completed := getCompletedOperations(executed, conf.Command, src)
+		if completed >= len(ops) {
+			fmt.Printf("Info: The %s operations of %s were already executed. Skipping them.\n", conf.Command, src.describe())
+			continue
+		}
+
+		if completed > 0 {
+			fmt.Printf("Info: The first %d of the %d %s operations of %s were already executed. Resuming after them.\n", completed, len(ops), conf.Command, src.describe())
+		}
+
+		for _, op := range ops[completed:] {
+			fmt.Printf("Info: %s.\n", op.Description)
+			execErr := op.Execute()
+			if execErr != nil {
+				return nil, execErr
+			}
+			completed += 1
+			count += 1
+
+			executed = setCompletedOperations(executed, conf.Command, src, completed)
+			saveErr := saveExecutedOperations(store, executed)
+			if saveErr != nil {
+				return nil, saveErr
+			}
+		}
+	}
+
+	if count == 0 {
+		fmt.Printf("Info: No pending %s operations were found.\n", conf.Command)
+		return nil, nil
+	}
+
+	fmt.Printf("Info: Executed %d %s operations. Running a plan to show their effect.\n", count, conf.Command)
+	_, summary, planErr := Plan(ctx, dir, conf)
+	return summary, planErr
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/Ferlab-Ste-Justine/terracd/config"
+)
+
+const VariablesFile = "terracd.auto.tfvars.json"
+
+//Converts the maps produced by the yaml parser, which can have non-string keys, to maps that can be serialized in json
+func toJsonValue(value interface{}) (interface{}, error) {
+	switch typedValue := value.(type) {
+	case map[interface{}]interface{}:
+		converted := map[string]interface{}{}
+		for key, val := range typedValue {
+			strKey, ok := key.(string)
+			if !ok {
+				return nil, errors.New(fmt.Sprintf("Map key %v is not a string", key))
+			}
+
+			convertedVal, err := toJsonValue(val)
+			if err != nil {
+				return nil, err
+			}
+			converted[strKey] = convertedVal
+		}
+		return converted, nil
+	case []interface{}:
+		converted := []interface{}{}
+		for _, val := range typedValue {
+			convertedVal, err := toJsonValue(val)
+			if err != nil {
+				return nil, err
+			}
+			converted = append(converted, convertedVal)
+		}
+		return converted, nil
+	default:
+		return value, nil
+	}
+}
+
+func readVarFile(varFile string) (map[string]interface{}, error) {
+	content, readErr := ioutil.ReadFile(varFile)
+	if readErr != nil {
+		return nil, errors.New(fmt.Sprintf("Error reading the variables file \"%s\": %s", varFile, readErr.Error()))
+	}
+
+	vars := map[interface{}]interface{}{}
+	unmarErr := yaml.Unmarshal(content, &vars)
+	if unmarErr != nil {
+		return nil, errors.New(fmt.Sprintf("Error parsing the variables file \"%s\". Note that the values it contains are not displayed.", varFile))
+	}
+
+	converted, convErr := toJsonValue(vars)
+	if convErr != nil {
+		return nil, errors.New(fmt.Sprintf("Error in the variables file \"%s\": %s", varFile, convErr.Error()))
+	}
+
+	return converted.(map[string]interface{}), nil
+}
+
+func getVariableValue(name string, variable config.Variable) (interface{}, error) {
+	if variable.File != "" {
+		content, readErr := ioutil.ReadFile(variable.File)
+		if readErr != nil {
+			return nil, errors.New(fmt.Sprintf("Error reading the value of variable \"%s\" from file: %s", name, readErr.Error()))
+		}
+
+		return strings.TrimRight(string(content), "\r\n"), nil
+	}
+
+	if variable.Env != "" {
+		value, ok := os.LookupEnv(variable.Env)
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("Environment variable \"%s\" containing the value of variable \"%s\" is not set", variable.Env, name))
+		}
+
+		return value, nil
+	}
+
+	value, convErr := toJsonValue(variable.Value)
+	if convErr != nil {
+		return nil, errors.New(fmt.Sprintf("Error in the value of variable \"%s\": %s", name, convErr.Error()))
+	}
+
+	return value, nil
+}
+
+//Generates a tfvars file from the variables files and variables of the configuration, the latter taking precedence.
+//Values are never displayed as they may be sensitive.
+func GenerateVariablesFile(conf config.Config, generatedDir string) error {
+	if len(conf.VarFiles) == 0 && len(conf.Variables) == 0 {
+		return nil
+	}
+
+	values := map[string]interface{}{}
+	for _, varFile := range conf.VarFiles {
+		fileValues, readErr := readVarFile(varFile)
+		if readErr != nil {
+			return readErr
+		}
+
+		for name, value := range fileValues {
+			values[name] = value
+		}
+	}
+
+	for name, variable := range conf.Variables {
+		value, valueErr := getVariableValue(name, variable)
+		if valueErr != nil {
+			return valueErr
+		}
+
+		values[name] = value
+	}
+
+	content, marErr := json.MarshalIndent(values, "", "  ")
+	if marErr != nil {
+		return errors.New(fmt.Sprintf("Error serializing the variables: %s", marErr.Error()))
+	}
+
+	writeErr := ioutil.WriteFile(path.Join(generatedDir, VariablesFile), content, 0600)
+	if writeErr != nil {
+		return errors.New(fmt.Sprintf("Error writing the variables file: %s", writeErr.Error()))
+	}
+
+	names := []string{}
+	for name, _ := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Printf("Info: Generated values for the following variables: %s\n", strings.Join(names, ", "))
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	yaml "gopkg.in/yaml.v2"
@@ -13,9 +14,12 @@ import (
 	"github.com/Ferlab-Ste-Justine/terracd/hook"
 	"github.com/Ferlab-Ste-Justine/terracd/metrics"
 	"github.com/Ferlab-Ste-Justine/terracd/cache"
+	"github.com/Ferlab-Ste-Justine/terracd/daemon"
 	"github.com/Ferlab-Ste-Justine/terracd/recurrence"
 	"github.com/Ferlab-Ste-Justine/terracd/source"
 	"github.com/Ferlab-Ste-Justine/terracd/state"
+	"github.com/Ferlab-Ste-Justine/terracd/terraform"
+	"github.com/Ferlab-Ste-Justine/terracd/trigger"
 )
 
 type ConfigTimeouts struct {
@@ -25,6 +29,10 @@ type ConfigTimeouts struct {
 	TerraformDestroy time.Duration `yaml:"terraform_destroy"`
 	TerraformPull    time.Duration `yaml:"terraform_pull"`
 	TerraformPush    time.Duration `yaml:"terraform_push"`
+	TerraformOutput  time.Duration `yaml:"terraform_output"`
+	TerraformImport  time.Duration `yaml:"terraform_import"`
+	TerraformStateMv time.Duration `yaml:"terraform_state_mv"`
+	TerraformStateRm time.Duration `yaml:"terraform_state_rm"`
 	Wait             time.Duration
 }
 
@@ -38,6 +46,42 @@ type CacheConfig struct {
 	GitSources cache.GitSourcesCacheConfig `yaml:"git_sources"`
 }
 
+type ApprovalConfig struct {
+	Required bool
+}
+
+type DestroyConfig struct {
+	Confirmation string
+}
+
+type HistoryConfig struct {
+	Size int
+}
+
+//Number of runs to retain in the history of the state
+func (conf *HistoryConfig) GetSize() int {
+	if conf.Size == 0 {
+		return 10
+	}
+
+	return conf.Size
+}
+
+type OutputsImport struct {
+	Stack      string
+	Variable   string
+	StateStore state.StateStoreConfig `yaml:"state_store"`
+	DataPath   string                 `yaml:"data_path"`
+}
+
+func (imp *OutputsImport) GetVariable() string {
+	if imp.Variable == "" {
+		return imp.Stack
+	}
+
+	return imp.Variable
+}
+
 type Config struct {
 	TerraformPath    string                      `yaml:"terraform_path"`
 	Sources          source.Sources
@@ -52,9 +96,35 @@ type Config struct {
 	Cache            CacheConfig                  
 	Metrics          metrics.MetricsClientConfig
 	DataPath string                              `yaml:"data_path"`
+	ChangeBudget     terraform.ChangeBudget      `yaml:"change_budget"`
+	Approval         ApprovalConfig
+	Daemon           daemon.DaemonConfig
+	Stacks           Stacks
+	ExportOutputs    bool                        `yaml:"export_outputs"`
+	ImportOutputs    []OutputsImport             `yaml:"import_outputs"`
+	Variables        Variables
+	VarFiles         []string                    `yaml:"var_files"`
+	History          HistoryConfig
+	Trigger          trigger.TriggerConfig
+	Destroy          DestroyConfig
+	Targets          []string
+	Replace          []string
+	StateOperations  terraform.StateOperations   `yaml:"state_operations"`
 }
 
-func getConfigFilePath() string {
+//Values that take precedence over the fields of the configuration file
+type Overrides struct {
+	ConfigFile       string
+	Command          string
+	WorkingDirectory string
+	DataPath         string
+}
+
+func (overrides *Overrides) getConfigFilePath() string {
+	if overrides.ConfigFile != "" {
+		return overrides.ConfigFile
+	}
+
 	path := os.Getenv("TERRACD_CONFIG_FILE")
 	if path == "" {
 		return "config.yml"
@@ -62,69 +132,180 @@ func getConfigFilePath() string {
 	return path
 }
 
-func GetConfig() (Config, error) {
-	var c Config
-
-	b, err := ioutil.ReadFile(getConfigFilePath())
-	if err != nil {
-		return c, errors.New(fmt.Sprintf("Error reading the configuration file: %s", err.Error()))
-	}
-	err = yaml.Unmarshal(b, &c)
-	if err != nil {
-		return c, errors.New(fmt.Sprintf("Error parsing the configuration file: %s", err.Error()))
+func (overrides *Overrides) apply(c *Config) {
+	if overrides.Command != "" {
+		c.Command = overrides.Command
 	}
 
-	if c.Command == "" {
-		c.Command = "apply"
+	if overrides.WorkingDirectory != "" {
+		c.WorkingDirectory = overrides.WorkingDirectory
 	}
 
-	if c.Command != "apply" && c.Command != "plan" && c.Command != "destroy" && c.Command != "wait" && c.Command != "migrate_backend" {
-		return c, errors.New("Valid command values can only be 'plan', 'apply', 'destroy', 'wait' or 'migrate_backend'")
+	if overrides.DataPath != "" {
+		c.DataPath = overrides.DataPath
 	}
+}
 
-	for _, thook := range []hook.TerminationHook{c.TerminationHooks.Success, c.TerminationHooks.Failure, c.TerminationHooks.Always} {
-		if (thook.HttpCall.Endpoint != "" && thook.HttpCall.Method == "") || (thook.HttpCall.Endpoint == "" && thook.HttpCall.Method != "") {
-			return c, errors.New("If an http call is defined in a termination hook, both the method and endpoint must be defined")
-		}
+//Returns whether the command executes state operations
+func IsStateOperationsCommand(command string) bool {
+	return command == "import" || command == "state_mv" || command == "state_rm"
+}
+
+func ValidateCommand(command string) error {
+	if command != "apply" && command != "plan" && command != "drift" && command != "destroy" && command != "wait" && command != "migrate_backend" && !IsStateOperationsCommand(command) {
+		return errors.New("Valid command values can only be 'plan', 'apply', 'drift', 'destroy', 'wait', 'migrate_backend', 'import', 'state_mv' or 'state_rm'")
 	}
 
+	return nil
+}
+
+func (c *Config) resolveWorkingDirectory() error {
 	if c.WorkingDirectory == "" {
 		wd, wdErr := os.Getwd()
 		if wdErr != nil {
-			return c, wdErr
+			return wdErr
 		}
 
 		c.WorkingDirectory = wd
 	}
 
+	var err error
 	c.WorkingDirectory, err = filepath.Abs(c.WorkingDirectory)
+	return err
+}
+
+//Fills in default values, resolves relative paths and validates the configuration
+func (c *Config) finalize() Problems {
+	problems := Problems{}
+
+	if c.Command == "" {
+		c.Command = "apply"
+	}
+
+	wdErr := c.resolveWorkingDirectory()
+	if wdErr != nil {
+		problems.AddErr("working_directory", wdErr)
+		return problems
+	}
+
+	if c.BackendMigration.NextBackend != "" && !filepath.IsAbs(c.BackendMigration.NextBackend) {
+		c.BackendMigration.NextBackend = filepath.Join(c.WorkingDirectory, c.BackendMigration.NextBackend)
+	}
+
+	if strings.ContainsRune(c.TerraformPath, filepath.Separator) && !filepath.IsAbs(c.TerraformPath) {
+		c.TerraformPath = filepath.Join(c.WorkingDirectory, c.TerraformPath)
+	}
+
+	problems = append(problems, c.validate()...)
+	if len(problems) > 0 {
+		return problems
+	}
+
+	problems.AddErr("cache.providers", c.Cache.Providers.Initialize())
+	problems.AddErr("cache.git_sources", c.Cache.GitSources.Initialize())
+
+	return problems
+}
+
+func (c *Config) validateOutputsImports() Problems {
+	problems := Problems{}
+
+	variables := map[string]bool{}
+	for idx, imp := range c.ImportOutputs {
+		impPath := fmt.Sprintf("import_outputs[%d]", idx)
+
+		if imp.Stack == "" {
+			problems.Add(impPath, "Each imported outputs entry must reference the stack producing them")
+			continue
+		}
+
+		if !imp.StateStore.IsDefined() {
+			problems.Add(joinYamlPath(impPath, "state_store"), fmt.Sprintf("The state store containing the outputs of stack \"%s\" must be defined", imp.Stack))
+		}
+
+		if imp.StateStore.Fs.IsDefined() && imp.DataPath == "" {
+			problems.Add(joinYamlPath(impPath, "data_path"), fmt.Sprintf("The data path of stack \"%s\" must be defined in order to read its outputs from its filesystem state store", imp.Stack))
+		}
+
+		if variables[imp.GetVariable()] {
+			problems.Add(joinYamlPath(impPath, "variable"), fmt.Sprintf("Outputs are imported more than once in variable \"%s\"", imp.GetVariable()))
+		}
+		variables[imp.GetVariable()] = true
+	}
+
+	return problems
+}
+
+func (c *Config) HasStacks() bool {
+	return len(c.Stacks) > 0
+}
+
+func readConfigFile(overrides Overrides) ([]byte, error) {
+	b, err := ioutil.ReadFile(overrides.getConfigFilePath())
 	if err != nil {
-		return c, err
+		return b, errors.New(fmt.Sprintf("Error reading the configuration file: %s", err.Error()))
 	}
 
-	if c.Recurrence.IsDefined() && (!c.StateStore.IsDefined()) {
-		return c, errors.New("If a reccurrence is defined, a state store must also be defined in order to enforce it")
+	return interpolate(b)
+}
+
+//Parses and validates the configuration, reporting all the problems found.
+//Unknown fields are reported as problems rather than silently ignored.
+func parseConfig(b []byte, overrides Overrides) (Config, Problems) {
+	var c Config
+	problems := Problems{}
+
+	content := map[interface{}]interface{}{}
+	err := yaml.UnmarshalStrict(b, &content)
+	if err != nil {
+		problems.Add("", fmt.Sprintf("Error parsing the configuration file: %s", err.Error()))
+		return c, problems
 	}
+	problems = append(problems, findUnknownFields(content, configType, "")...)
 
-	if c.Cache.Providers.IsDefined() && (!c.StateStore.IsDefined()) {
-		return c, errors.New("If providers cache is defined, a state store must also be defined in order to manage it")
+	err = yaml.Unmarshal(b, &c)
+	if err != nil {
+		problems.Add("", fmt.Sprintf("Error parsing the configuration file: %s", err.Error()))
+		return c, problems
+	}
+	overrides.apply(&c)
+
+	if !c.HasStacks() {
+		finalizeProblems := c.finalize()
+		if len(finalizeProblems) > 0 {
+			return c, append(problems, finalizeProblems...)
+		}
+
+		return c, append(problems, c.validateOutputsImports()...)
 	}
 
-	for _, src := range c.Sources {
-		if src.GetType() == source.TypeUndefined {
-			return c, errors.New("One of the listed sources could not be properly interpreted")
+	wdErr := c.resolveWorkingDirectory()
+	if wdErr != nil {
+		problems.AddErr("working_directory", wdErr)
+		return c, problems
+	}
+
+	if c.DataPath != "" {
+		c.DataPath, err = filepath.Abs(c.DataPath)
+		if err != nil {
+			problems.AddErr("data_path", err)
+			return c, problems
 		}
 	}
 
-	providersCacheInitErr := c.Cache.Providers.Initialize()
-	if providersCacheInitErr != nil {
-		return c, providersCacheInitErr
+	if c.Daemon.IsDefined() {
+		problems.AddErr("daemon", c.Daemon.Validate())
 	}
 
-	gitSourcesCacheInitErr := c.Cache.GitSources.Initialize()
-	if gitSourcesCacheInitErr != nil {
-		return c, gitSourcesCacheInitErr
+	return c, append(problems, c.loadStacks(content, overrides)...)
+}
+
+func GetConfig(overrides Overrides) (Config, error) {
+	b, err := readConfigFile(overrides)
+	if err != nil {
+		return Config{}, err
 	}
 
-	return c, nil
+	c, problems := parseConfig(b, overrides)
+	return c, problems.Err()
 }
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+var (
+	substitutionRegex = regexp.MustCompile(`\$\$\{|\$\{([^}]*)\}`)
+	envVarNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+func getSubstitutionValue(expr string) (string, error) {
+	if strings.HasPrefix(expr, "file:") {
+		filePath := strings.TrimPrefix(expr, "file:")
+		content, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			return "", errors.New(fmt.Sprintf("Error reading file \"%s\" for substitution: %s", filePath, err.Error()))
+		}
+
+		return strings.TrimRight(string(content), "\r\n"), nil
+	}
+
+	if !envVarNameRegex.MatchString(expr) {
+		return "", errors.New(fmt.Sprintf("Invalid substitution \"${%s}\": expected an environment variable name or file:<path>", expr))
+	}
+
+	value, ok := os.LookupEnv(expr)
+	if !ok {
+		return "", errors.New(fmt.Sprintf("Environment variable \"%s\" is not set", expr))
+	}
+
+	return value, nil
+}
+
+//Performs the substitutions in a single value. Returns whether the value consisted of a single substitution expression.
+func substitute(value string, problems *[]string) (string, bool) {
+	result := ""
+	last := 0
+
+	matches := substitutionRegex.FindAllStringSubmatchIndex(value, -1)
+	for _, match := range matches {
+		result += value[last:match[0]]
+		last = match[1]
+
+		if match[2] == -1 {
+			result += "${"
+			continue
+		}
+
+		subValue, err := getSubstitutionValue(value[match[2]:match[3]])
+		if err != nil {
+			*problems = append(*problems, err.Error())
+			continue
+		}
+		result += subValue
+	}
+	result += value[last:]
+
+	whole := len(matches) == 1 && matches[0][0] == 0 && matches[0][1] == len(value) && matches[0][2] != -1
+	return result, whole
+}
+
+//Performs the substitutions in the scalar values of the node and its children. Mapping keys and comments are left untouched.
+func interpolateNode(node *yamlv3.Node, problems *[]string) {
+	switch node.Kind {
+	case yamlv3.ScalarNode:
+		if !substitutionRegex.MatchString(node.Value) {
+			return
+		}
+
+		value, whole := substitute(node.Value, problems)
+		node.Value = value
+
+		//An unquoted value made of a single expression is typed according to its substituted value, like a value written in the file would be.
+		//Otherwise, the value remains a string no matter what it contains.
+		if whole && node.Style == 0 {
+			node.Tag = ""
+		} else {
+			node.Tag = "!!str"
+		}
+	case yamlv3.MappingNode:
+		for idx := 1; idx < len(node.Content); idx += 2 {
+			interpolateNode(node.Content[idx], problems)
+		}
+	case yamlv3.DocumentNode, yamlv3.SequenceNode:
+		for _, child := range node.Content {
+			interpolateNode(child, problems)
+		}
+	}
+}
+
+//Replaces ${ENV_VAR} and ${file:/path} expressions in the values of the configuration with the value of the environment variable or the content of the file.
+//$${ is replaced with a literal ${.
+//The substitutions are performed on the parsed values so that the substituted content cannot alter the structure of the file.
+func interpolate(content []byte) ([]byte, error) {
+	if !substitutionRegex.Match(content) {
+		return content, nil
+	}
+
+	var doc yamlv3.Node
+	err := yamlv3.Unmarshal(content, &doc)
+	if err != nil || doc.Kind == 0 {
+		//Parsing errors are reported when the configuration is parsed
+		return content, nil
+	}
+
+	problems := []string{}
+	interpolateNode(&doc, &problems)
+
+	if len(problems) > 0 {
+		return nil, errors.New(fmt.Sprintf("Error performing substitutions in the configuration file:\n%s", strings.Join(problems, "\n")))
+	}
+
+	var result bytes.Buffer
+	encoder := yamlv3.NewEncoder(&result)
+	encoder.SetIndent(2)
+	err = encoder.Encode(&doc)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Error serializing the configuration file after substitutions: %s", err.Error()))
+	}
+	encoder.Close()
+
+	return result.Bytes(), nil
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"os"
+	"path"
+	"reflect"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestInterpolate(t *testing.T) {
+	os.Setenv("TERRACD_TEST_VALUE", "value")
+	os.Setenv("TERRACD_TEST_NUMBER", "3")
+	os.Setenv("TERRACD_TEST_MULTILINE", "first line\nsecond: line")
+	os.Setenv("TERRACD_TEST_MAPPING", "injected: true")
+	os.Setenv("TERRACD_TEST_COMMENT", "before # after")
+	os.Setenv("TERRACD_TEST_FLOW", "{injected: true}")
+	os.Setenv("TERRACD_TEST_SEQUENCE", "[injected]")
+	os.Setenv("TERRACD_TEST_NESTED", "${TERRACD_TEST_VALUE}")
+	os.Unsetenv("TERRACD_TEST_UNSET")
+	defer func() {
+		for _, name := range []string{"TERRACD_TEST_VALUE", "TERRACD_TEST_NUMBER", "TERRACD_TEST_MULTILINE", "TERRACD_TEST_MAPPING", "TERRACD_TEST_COMMENT", "TERRACD_TEST_FLOW", "TERRACD_TEST_SEQUENCE", "TERRACD_TEST_NESTED"} {
+			os.Unsetenv(name)
+		}
+	}()
+
+	filePath := path.Join(t.TempDir(), "password")
+	err := os.WriteFile(filePath, []byte("secret: \"value\"\n\n"), 0600)
+	if err != nil {
+		t.Errorf("%s", err.Error())
+		return
+	}
+
+	tests := []struct {
+		name     string
+		content  string
+		expected map[interface{}]interface{}
+	}{
+		{"no substitution", "key: value", map[interface{}]interface{}{"key": "value"}},
+		{"environment variable", "key: ${TERRACD_TEST_VALUE}", map[interface{}]interface{}{"key": "value"}},
+		{"quoted environment variable", "key: \"${TERRACD_TEST_VALUE}\"", map[interface{}]interface{}{"key": "value"}},
+		{"environment variable in text", "key: prefix-${TERRACD_TEST_VALUE}-suffix", map[interface{}]interface{}{"key": "prefix-value-suffix"}},
+		{"several environment variables", "key: ${TERRACD_TEST_VALUE}/${TERRACD_TEST_NUMBER}", map[interface{}]interface{}{"key": "value/3"}},
+		{"number", "key: ${TERRACD_TEST_NUMBER}", map[interface{}]interface{}{"key": 3}},
+		{"quoted number", "key: \"${TERRACD_TEST_NUMBER}\"", map[interface{}]interface{}{"key": "3"}},
+		{"file", "key: ${file:" + filePath + "}", map[interface{}]interface{}{"key": "secret: \"value\""}},
+		{"escape", "key: $${TERRACD_TEST_VALUE}", map[interface{}]interface{}{"key": "${TERRACD_TEST_VALUE}"}},
+		{"escape next to a substitution", "key: $${TERRACD_TEST_VALUE}=${TERRACD_TEST_VALUE}", map[interface{}]interface{}{"key": "${TERRACD_TEST_VALUE}=value"}},
+		{"escape of an unset variable", "key: $${TERRACD_TEST_UNSET}", map[interface{}]interface{}{"key": "${TERRACD_TEST_UNSET}"}},
+		{"value with an expression is not substituted again", "key: ${TERRACD_TEST_NESTED}", map[interface{}]interface{}{"key": "${TERRACD_TEST_VALUE}"}},
+		{"value with a newline", "key: ${TERRACD_TEST_MULTILINE}\nother: value", map[interface{}]interface{}{"key": "first line\nsecond: line", "other": "value"}},
+		{"value with a mapping", "key: ${TERRACD_TEST_MAPPING}", map[interface{}]interface{}{"key": "injected: true"}},
+		{"value with a comment", "key: ${TERRACD_TEST_COMMENT}", map[interface{}]interface{}{"key": "before # after"}},
+		{"value with a flow mapping", "key: ${TERRACD_TEST_FLOW}", map[interface{}]interface{}{"key": "{injected: true}"}},
+		{"value with a flow sequence", "key: ${TERRACD_TEST_SEQUENCE}", map[interface{}]interface{}{"key": "[injected]"}},
+		{"values in sequences", "key:\n  - ${TERRACD_TEST_VALUE}\n  - name: ${TERRACD_TEST_MAPPING}", map[interface{}]interface{}{"key": []interface{}{"value", map[interface{}]interface{}{"name": "injected: true"}}}},
+		{"unset variable in a comment", "#key: ${TERRACD_TEST_UNSET}\nkey: value # ${TERRACD_TEST_UNSET}", map[interface{}]interface{}{"key": "value"}},
+		{"mapping key", "${TERRACD_TEST_VALUE}: value", map[interface{}]interface{}{"${TERRACD_TEST_VALUE}": "value"}},
+	}
+
+	for _, test := range tests {
+		result, err := interpolate([]byte(test.content))
+		if err != nil {
+			t.Errorf("Expected substitutions with %s to succeed and they didn't: %s", test.name, err.Error())
+			continue
+		}
+
+		content := map[interface{}]interface{}{}
+		err = yaml.UnmarshalStrict(result, &content)
+		if err != nil {
+			t.Errorf("Expected the result of substitutions with %s to be valid yaml and it wasn't: %s", test.name, err.Error())
+			continue
+		}
+
+		if !reflect.DeepEqual(content, test.expected) {
+			t.Errorf("Expected substitutions with %s to result in %v and it resulted in %v", test.name, test.expected, content)
+		}
+	}
+}
+
+func TestInterpolateErrors(t *testing.T) {
+	os.Unsetenv("TERRACD_TEST_UNSET")
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"unset variable", "key: ${TERRACD_TEST_UNSET}"},
+		{"unset variable in a sequence", "key:\n  - ${TERRACD_TEST_UNSET}"},
+		{"invalid variable name", "key: ${TERRACD-TEST}"},
+		{"empty expression", "key: ${}"},
+		{"missing file", "key: ${file:" + path.Join(t.TempDir(), "missing") + "}"},
+	}
+
+	for _, test := range tests {
+		_, err := interpolate([]byte(test.content))
+		if err == nil {
+			t.Errorf("Expected substitutions with %s to fail and they didn't", test.name)
+		}
+	}
+}
@@ -0,0 +1,324 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/Ferlab-Ste-Justine/terracd/source"
+	"github.com/Ferlab-Ste-Justine/terracd/trigger"
+)
+
+type Stack struct {
+	Name      string
+	DependsOn []string `yaml:"depends_on"`
+	Config    Config   `yaml:"-"`
+	raw       map[interface{}]interface{}
+}
+
+func (stack *Stack) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var meta struct {
+		Name      string
+		DependsOn []string `yaml:"depends_on"`
+	}
+	metaErr := unmarshal(&meta)
+	if metaErr != nil {
+		return metaErr
+	}
+
+	raw := map[interface{}]interface{}{}
+	rawErr := unmarshal(&raw)
+	if rawErr != nil {
+		return rawErr
+	}
+
+	delete(raw, "name")
+	delete(raw, "depends_on")
+
+	stack.Name = meta.Name
+	stack.DependsOn = meta.DependsOn
+	stack.raw = raw
+	return nil
+}
+
+type Stacks []Stack
+
+//Returns the sources of the configuration, including those of its stacks
+func (c *Config) GetAllSources() source.Sources {
+	sources := source.Sources{}
+	sources = append(sources, c.Sources...)
+	for _, stack := range c.Stacks {
+		sources = append(sources, stack.Config.Sources...)
+	}
+
+	return sources
+}
+
+//Returns the triggers of the configuration, which are those of its stacks if it has any
+func (c *Config) GetAllTriggers() []trigger.TriggerConfig {
+	if !c.HasStacks() {
+		return []trigger.TriggerConfig{c.Trigger}
+	}
+
+	triggers := []trigger.TriggerConfig{}
+	for _, stack := range c.Stacks {
+		triggers = append(triggers, stack.Config.Trigger)
+	}
+
+	return triggers
+}
+
+//Returns the stacks sorted such that each stack comes after the stacks it depends on
+func (stacks Stacks) Sort() (Stacks, error) {
+	byName := map[string]Stack{}
+	for _, stack := range stacks {
+		if _, ok := byName[stack.Name]; ok {
+			return Stacks{}, errors.New(fmt.Sprintf("Stack name \"%s\" is defined more than once", stack.Name))
+		}
+		byName[stack.Name] = stack
+	}
+
+	sorted := Stacks{}
+	visited := map[string]bool{}
+	visiting := map[string]bool{}
+
+	var visit func(stack Stack, chain []string) error
+	visit = func(stack Stack, chain []string) error {
+		if visited[stack.Name] {
+			return nil
+		}
+
+		chain = append(chain, stack.Name)
+		if visiting[stack.Name] {
+			return errors.New(fmt.Sprintf("Stacks have a circular dependency: %s", strings.Join(chain, " -> ")))
+		}
+		visiting[stack.Name] = true
+
+		for _, dep := range stack.DependsOn {
+			depStack, ok := byName[dep]
+			if !ok {
+				return errors.New(fmt.Sprintf("Stack \"%s\" depends on stack \"%s\" which is not defined", stack.Name, dep))
+			}
+
+			visitErr := visit(depStack, chain)
+			if visitErr != nil {
+				return visitErr
+			}
+		}
+
+		visiting[stack.Name] = false
+		visited[stack.Name] = true
+		sorted = append(sorted, stack)
+		return nil
+	}
+
+	for _, stack := range stacks {
+		visitErr := visit(stack, []string{})
+		if visitErr != nil {
+			return sorted, visitErr
+		}
+	}
+
+	return sorted, nil
+}
+
+//Merges the override values in the base values, recursing in maps and replacing everything else
+func mergeYamlMaps(base map[interface{}]interface{}, override map[interface{}]interface{}) map[interface{}]interface{} {
+	merged := map[interface{}]interface{}{}
+	for key, val := range base {
+		merged[key] = val
+	}
+
+	for key, val := range override {
+		baseMap, baseIsMap := merged[key].(map[interface{}]interface{})
+		overrideMap, overrideIsMap := val.(map[interface{}]interface{})
+		if baseIsMap && overrideIsMap {
+			merged[key] = mergeYamlMaps(baseMap, overrideMap)
+		} else {
+			merged[key] = val
+		}
+	}
+
+	return merged
+}
+
+//Prevents stacks from colliding on identifiers inherited from the top-level configuration
+func (stack *Stack) namespace(parent *Config) {
+	conf := &stack.Config
+
+	if conf.StateStore.Etcd.Prefix == parent.StateStore.Etcd.Prefix && conf.StateStore.Etcd.IsDefined() {
+		conf.StateStore.Etcd.Prefix = conf.StateStore.Etcd.Prefix + stack.Name + "/"
+	}
+
+	if conf.StateStore.S3.Path == parent.StateStore.S3.Path && conf.StateStore.S3.IsDefined() {
+		conf.StateStore.S3.Path = path.Join(conf.StateStore.S3.Path, stack.Name)
+	}
+
+	if conf.Cache.Providers.S3.Path == parent.Cache.Providers.S3.Path && conf.Cache.Providers.S3.IsDefined() {
+		conf.Cache.Providers.S3.Path = path.Join(conf.Cache.Providers.S3.Path, stack.Name)
+	}
+
+	if conf.Cache.GitSources.S3.Path == parent.Cache.GitSources.S3.Path && conf.Cache.GitSources.S3.IsDefined() {
+		conf.Cache.GitSources.S3.Path = path.Join(conf.Cache.GitSources.S3.Path, stack.Name)
+	}
+
+	if conf.Metrics.JobName == parent.Metrics.JobName && conf.Metrics.JobName != "" {
+		conf.Metrics.JobName = conf.Metrics.JobName + "_" + stack.Name
+	}
+}
+
+//Imports of outputs produced by other stacks of the configuration default to their state store and make the stack depend on them
+func (stack *Stack) resolveOutputsImports(stacks Stacks) Problems {
+	problems := Problems{}
+
+	for idx, _ := range stack.Config.ImportOutputs {
+		imp := &stack.Config.ImportOutputs[idx]
+		if imp.StateStore.IsDefined() {
+			continue
+		}
+
+		for _, producer := range stacks {
+			if producer.Name != imp.Stack {
+				continue
+			}
+
+			if !producer.Config.ExportOutputs {
+				problems.Add(fmt.Sprintf("import_outputs[%d].stack", idx), fmt.Sprintf("Stack \"%s\" does not export its outputs", producer.Name))
+				continue
+			}
+
+			imp.StateStore = producer.Config.StateStore
+			imp.DataPath = producer.Config.DataPath
+			if imp.DataPath == "" {
+				imp.DataPath = producer.Config.WorkingDirectory
+			}
+
+			dependent := false
+			for _, dep := range stack.DependsOn {
+				if dep == producer.Name {
+					dependent = true
+				}
+			}
+			if !dependent {
+				stack.DependsOn = append(stack.DependsOn, producer.Name)
+			}
+		}
+	}
+
+	return problems
+}
+
+//Assembles the configuration of each stack from the top-level fields and the fields of the stack.
+//Only the command override applies to the stacks as they derive their working directory and data path from the top-level ones.
+func (c *Config) loadStacks(content map[interface{}]interface{}, overrides Overrides) Problems {
+	problems := Problems{}
+
+	base := map[interface{}]interface{}{}
+	for key, val := range content {
+		base[key] = val
+	}
+
+	delete(base, "stacks")
+	delete(base, "daemon")
+	delete(base, "working_directory")
+	delete(base, "data_path")
+
+	names := map[string]bool{}
+	workDirs := map[string]string{}
+	for idx, _ := range c.Stacks {
+		stack := &c.Stacks[idx]
+		stackPath := fmt.Sprintf("stacks[%d]", idx)
+
+		if stack.Name == "" {
+			problems.Add(joinYamlPath(stackPath, "name"), "Each stack must have a name")
+			continue
+		}
+
+		if strings.ContainsAny(stack.Name, "/\\") {
+			problems.Add(joinYamlPath(stackPath, "name"), fmt.Sprintf("Stack name \"%s\" cannot contain path separators", stack.Name))
+			continue
+		}
+
+		if names[stack.Name] {
+			problems.Add(joinYamlPath(stackPath, "name"), fmt.Sprintf("Stack name \"%s\" is defined more than once", stack.Name))
+			continue
+		}
+		names[stack.Name] = true
+
+		invalid := false
+		for _, key := range []string{"stacks", "daemon"} {
+			if _, ok := stack.raw[key]; ok {
+				problems.Add(joinYamlPath(stackPath, key), "This field is only valid at the top-level")
+				invalid = true
+			}
+		}
+		if invalid {
+			continue
+		}
+
+		merged := mergeYamlMaps(base, stack.raw)
+		if _, ok := merged["working_directory"]; !ok {
+			merged["working_directory"] = path.Join(c.WorkingDirectory, stack.Name)
+		}
+		if _, ok := merged["data_path"]; !ok && c.DataPath != "" {
+			merged["data_path"] = path.Join(c.DataPath, stack.Name)
+		}
+
+		mergedContent, marErr := yaml.Marshal(merged)
+		if marErr != nil {
+			problems.Add(stackPath, fmt.Sprintf("Error assembling the configuration of the stack: %s", marErr.Error()))
+			continue
+		}
+
+		unmarErr := yaml.Unmarshal(mergedContent, &stack.Config)
+		if unmarErr != nil {
+			problems.Add(stackPath, fmt.Sprintf("Error parsing the configuration of the stack: %s", unmarErr.Error()))
+			continue
+		}
+		if overrides.Command != "" {
+			stack.Config.Command = overrides.Command
+		}
+
+		stack.namespace(c)
+
+		finalizeProblems := stack.Config.finalize()
+		if len(finalizeProblems) > 0 {
+			problems.Merge(stackPath, finalizeProblems)
+			continue
+		}
+
+		if other, ok := workDirs[stack.Config.WorkingDirectory]; ok {
+			problems.Add(joinYamlPath(stackPath, "working_directory"), fmt.Sprintf("Stacks \"%s\" and \"%s\" cannot share the same working directory", other, stack.Name))
+			continue
+		}
+		workDirs[stack.Config.WorkingDirectory] = stack.Name
+	}
+
+	if len(problems) > 0 {
+		return problems
+	}
+
+	for idx, _ := range c.Stacks {
+		stack := &c.Stacks[idx]
+		stackPath := fmt.Sprintf("stacks[%d]", idx)
+
+		resolveProblems := stack.resolveOutputsImports(c.Stacks)
+		if len(resolveProblems) > 0 {
+			problems.Merge(stackPath, resolveProblems)
+			continue
+		}
+
+		problems.Merge(stackPath, stack.Config.validateOutputsImports())
+	}
+
+	if len(problems) > 0 {
+		return problems
+	}
+
+	_, sortErr := c.Stacks.Sort()
+	problems.AddErr("stacks", sortErr)
+	return problems
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func getSortTestStacks(deps map[string][]string, names ...string) Stacks {
+	stacks := Stacks{}
+	for _, name := range names {
+		stacks = append(stacks, Stack{Name: name, DependsOn: deps[name]})
+	}
+
+	return stacks
+}
+
+func TestStacksSort(t *testing.T) {
+	tests := []struct {
+		name   string
+		stacks Stacks
+	}{
+		{"no stacks", Stacks{}},
+		{"no dependencies", getSortTestStacks(map[string][]string{}, "dns", "network", "database")},
+		{"dependencies defined before their dependents", getSortTestStacks(map[string][]string{"network": {"dns"}, "database": {"network"}}, "dns", "network", "database")},
+		{"dependencies defined after their dependents", getSortTestStacks(map[string][]string{"network": {"dns"}, "database": {"network"}}, "database", "network", "dns")},
+		{"shared dependency", getSortTestStacks(map[string][]string{"database": {"dns", "network"}, "network": {"dns"}, "app": {"database", "dns"}}, "app", "database", "network", "dns")},
+	}
+
+	for _, test := range tests {
+		sorted, err := test.stacks.Sort()
+		if err != nil {
+			t.Errorf("Expected sorting stacks with %s to succeed and it didn't: %s", test.name, err.Error())
+			continue
+		}
+
+		if len(sorted) != len(test.stacks) {
+			t.Errorf("Expected sorting stacks with %s to keep %d stacks and it kept %d", test.name, len(test.stacks), len(sorted))
+			continue
+		}
+
+		positions := map[string]int{}
+		for idx, stack := range sorted {
+			positions[stack.Name] = idx
+		}
+
+		for _, stack := range sorted {
+			for _, dep := range stack.DependsOn {
+				if positions[dep] > positions[stack.Name] {
+					t.Errorf("Expected stack \"%s\" to come after its dependency \"%s\" with %s and it didn't", stack.Name, dep, test.name)
+				}
+			}
+		}
+	}
+}
+
+func TestStacksSortErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		stacks  Stacks
+		message string
+	}{
+		{"stack depending on itself", getSortTestStacks(map[string][]string{"dns": {"dns"}}, "dns"), "circular dependency: dns -> dns"},
+		{"cycle of two stacks", getSortTestStacks(map[string][]string{"dns": {"network"}, "network": {"dns"}}, "dns", "network"), "circular dependency: dns -> network -> dns"},
+		{"cycle further down the dependencies", getSortTestStacks(map[string][]string{"app": {"database"}, "database": {"network"}, "network": {"dns"}, "dns": {"database"}}, "app", "database", "network", "dns"), "circular dependency: app -> database -> network -> dns -> database"},
+		{"missing dependency", getSortTestStacks(map[string][]string{"network": {"dns"}}, "network"), "Stack \"network\" depends on stack \"dns\" which is not defined"},
+		{"missing dependency of a dependency", getSortTestStacks(map[string][]string{"database": {"network"}, "network": {"dns"}}, "database", "network"), "Stack \"network\" depends on stack \"dns\" which is not defined"},
+		{"duplicate names", getSortTestStacks(map[string][]string{}, "dns", "network", "dns"), "Stack name \"dns\" is defined more than once"},
+		{"duplicate names with dependencies", getSortTestStacks(map[string][]string{"network": {"dns"}}, "network", "dns", "network"), "Stack name \"network\" is defined more than once"},
+	}
+
+	for _, test := range tests {
+		_, err := test.stacks.Sort()
+		if err == nil {
+			t.Errorf("Expected sorting stacks with %s to fail and it didn't", test.name)
+			continue
+		}
+
+		if !strings.Contains(err.Error(), test.message) {
+			t.Errorf("Expected sorting stacks with %s to fail with \"%s\" and it failed with \"%s\"", test.name, test.message, err.Error())
+		}
+	}
+}
@@ -0,0 +1,498 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Ferlab-Ste-Justine/terracd/auth"
+	"github.com/Ferlab-Ste-Justine/terracd/hook"
+	"github.com/Ferlab-Ste-Justine/terracd/s3"
+	"github.com/Ferlab-Ste-Justine/terracd/source"
+)
+
+type Problem struct {
+	Path    string
+	Message string
+}
+
+func (problem *Problem) ToString() string {
+	if problem.Path == "" {
+		return problem.Message
+	}
+
+	return fmt.Sprintf("%s: %s", problem.Path, problem.Message)
+}
+
+type Problems []Problem
+
+func (problems *Problems) Add(path string, message string) {
+	*problems = append(*problems, Problem{Path: path, Message: message})
+}
+
+func (problems *Problems) AddErr(path string, err error) {
+	if err != nil {
+		problems.Add(path, err.Error())
+	}
+}
+
+//Adds the problems of a nested part of the configuration, prefixing their path with the path of that part
+func (problems *Problems) Merge(prefix string, others Problems) {
+	for _, other := range others {
+		problems.Add(joinYamlPath(prefix, other.Path), other.Message)
+	}
+}
+
+//Returns an error listing all the problems, sorted by path, or nil if there are none
+func (problems Problems) Err() error {
+	if len(problems) == 0 {
+		return nil
+	}
+
+	sorted := append(Problems{}, problems...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Path < sorted[j].Path
+	})
+
+	lines := []string{}
+	for _, problem := range sorted {
+		lines = append(lines, fmt.Sprintf("  - %s", problem.ToString()))
+	}
+
+	return errors.New(fmt.Sprintf("The configuration has the following problems:\n%s", strings.Join(lines, "\n")))
+}
+
+func joinYamlPath(prefix string, key string) string {
+	if prefix == "" {
+		return key
+	}
+
+	if key == "" || strings.HasPrefix(key, "[") {
+		return prefix + key
+	}
+
+	return prefix + "." + key
+}
+
+var (
+	configType   = reflect.TypeOf(Config{})
+	stackType    = reflect.TypeOf(Stack{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+type yamlField struct {
+	name  string
+	index []int
+}
+
+//Returns the fields of a struct under the names the yaml parser expects them
+func getYamlFields(t reflect.Type) []yamlField {
+	fields := []yamlField{}
+	for idx := 0; idx < t.NumField(); idx++ {
+		field := t.Field(idx)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		tag := strings.Split(field.Tag.Get("yaml"), ",")
+		if tag[0] == "-" {
+			continue
+		}
+
+		inline := false
+		for _, opt := range tag[1:] {
+			if opt == "inline" {
+				inline = true
+			}
+		}
+
+		if inline {
+			for _, inlined := range getYamlFields(field.Type) {
+				fields = append(fields, yamlField{inlined.name, append([]int{idx}, inlined.index...)})
+			}
+			continue
+		}
+
+		name := tag[0]
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields = append(fields, yamlField{name, []int{idx}})
+	}
+
+	return fields
+}
+
+//Reports the keys of the parsed yaml content that do not match any field of the type they are parsed into
+func findUnknownFields(value interface{}, t reflect.Type, path string) Problems {
+	problems := Problems{}
+
+	if t == stackType {
+		entries, ok := value.(map[interface{}]interface{})
+		if !ok {
+			return problems
+		}
+
+		conf := map[interface{}]interface{}{}
+		for key, val := range entries {
+			if key != "name" && key != "depends_on" {
+				conf[key] = val
+			}
+		}
+
+		return findUnknownFields(conf, configType, path)
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return findUnknownFields(value, t.Elem(), path)
+	case reflect.Struct:
+		entries, ok := value.(map[interface{}]interface{})
+		if !ok {
+			return problems
+		}
+
+		fields := map[string]reflect.Type{}
+		for _, field := range getYamlFields(t) {
+			fields[field.name] = t.FieldByIndex(field.index).Type
+		}
+
+		for key, val := range entries {
+			name := fmt.Sprintf("%v", key)
+			fieldType, known := fields[name]
+			if !known {
+				problems.Add(joinYamlPath(path, name), "Unknown field")
+				continue
+			}
+
+			problems = append(problems, findUnknownFields(val, fieldType, joinYamlPath(path, name))...)
+		}
+	case reflect.Map:
+		entries, ok := value.(map[interface{}]interface{})
+		if !ok {
+			return problems
+		}
+
+		for key, val := range entries {
+			problems = append(problems, findUnknownFields(val, t.Elem(), joinYamlPath(path, fmt.Sprintf("%v", key)))...)
+		}
+	case reflect.Slice:
+		items, ok := value.([]interface{})
+		if !ok {
+			return problems
+		}
+
+		for idx, item := range items {
+			problems = append(problems, findUnknownFields(item, t.Elem(), fmt.Sprintf("%s[%d]", path, idx))...)
+		}
+	}
+
+	return problems
+}
+
+//Reports the durations of the configuration that are negative
+func findNegativeDurations(value reflect.Value, path string) Problems {
+	problems := Problems{}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		for _, field := range getYamlFields(value.Type()) {
+			fieldValue := value.FieldByIndex(field.index)
+			fieldPath := joinYamlPath(path, field.name)
+			if fieldValue.Type() == durationType {
+				if fieldValue.Int() < 0 {
+					problems.Add(fieldPath, fmt.Sprintf("Duration cannot be negative, got %s", time.Duration(fieldValue.Int()).String()))
+				}
+				continue
+			}
+
+			problems = append(problems, findNegativeDurations(fieldValue, fieldPath)...)
+		}
+	case reflect.Map:
+		for _, key := range value.MapKeys() {
+			problems = append(problems, findNegativeDurations(value.MapIndex(key), joinYamlPath(path, fmt.Sprintf("%v", key.Interface())))...)
+		}
+	case reflect.Slice:
+		for idx := 0; idx < value.Len(); idx++ {
+			problems = append(problems, findNegativeDurations(value.Index(idx), fmt.Sprintf("%s[%d]", path, idx))...)
+		}
+	}
+
+	return problems
+}
+
+//Reports the durations that are not negative, but are zero or too short for the feature using them to work
+func (c *Config) validateDurations() Problems {
+	problems := Problems{}
+
+	if c.StateStore.Lock.Ttl > 0 && c.StateStore.Lock.Ttl < time.Second {
+		problems.Add("state_store.lock.ttl", "The lock ttl must be at least 1s as etcd leases have a granularity of a second and the lock is renewed every third of its ttl")
+	}
+
+	if c.StateStore.Etcd.IsDefined() && c.StateStore.Etcd.RequestTimeout == 0 {
+		problems.Add("state_store.etcd.request_timeout", "The etcd request timeout must be defined as every etcd request would fail immediately otherwise")
+	}
+
+	if c.Trigger.Etcd.IsDefined() && c.Trigger.Etcd.RequestTimeout == 0 {
+		problems.Add("trigger.etcd.request_timeout", "The etcd request timeout must be defined as every etcd request would fail immediately otherwise")
+	}
+
+	if c.Recurrence.RetryBackoff.Max > 0 && c.Recurrence.RetryBackoff.Initial == 0 {
+		problems.Add("recurrence.retry_backoff.initial", "The initial retry delay must be defined for the retry backoff to apply")
+	}
+
+	if c.Daemon.IsDefined() {
+		if c.Daemon.Interval > 0 && c.Daemon.Interval < time.Second {
+			problems.Add("daemon.interval", "The daemon interval must be at least 1s")
+		}
+	} else if c.Daemon.Server.IsDefined() || c.Daemon.ShutdownTimeout > 0 || c.Daemon.Timezone != "" {
+		problems.Add("daemon", "The daemon must define a positive interval or a schedule")
+	}
+
+	if c.Daemon.Server.Webhook.Debounce > 0 && c.Daemon.Server.Webhook.Debounce < time.Second {
+		problems.Add("daemon.server.webhook.debounce", "The webhook debounce must be at least 1s so that a burst of pushes triggers a single execution")
+	}
+
+	return problems
+}
+
+func (c *Config) validateTerminationHooks() Problems {
+	problems := Problems{}
+
+	hooks := map[string]hook.TerminationHook{
+		"success": c.TerminationHooks.Success,
+		"failure": c.TerminationHooks.Failure,
+		"skip": c.TerminationHooks.Skip,
+		"always": c.TerminationHooks.Always,
+	}
+	for name, thook := range hooks {
+		if (thook.HttpCall.Endpoint != "" && thook.HttpCall.Method == "") || (thook.HttpCall.Endpoint == "" && thook.HttpCall.Method != "") {
+			problems.Add(joinYamlPath("termination_hooks", name+".http_call"), "If an http call is defined in a termination hook, both the method and endpoint must be defined")
+		}
+
+		if name != "always" && thook.IsDefined() && c.TerminationHooks.Always.IsDefined() {
+			problems.Add(joinYamlPath("termination_hooks", name), "This hook would never run as the always hook takes precedence over it")
+		}
+	}
+
+	return problems
+}
+
+//Validates the configuration, reporting all the problems found
+func (c *Config) validate() Problems {
+	problems := Problems{}
+
+	problems.AddErr("command", ValidateCommand(c.Command))
+
+	problems = append(problems, c.validateTerminationHooks()...)
+
+	if c.Command == "migrate_backend" {
+		if c.BackendMigration.CurrentBackend == "" {
+			problems.Add("backend_migration.current_backend", "The current backend file must be defined in order to migrate the backend")
+		}
+
+		if c.BackendMigration.NextBackend == "" {
+			problems.Add("backend_migration.next_backend", "The next backend file must be defined in order to migrate the backend")
+		}
+	}
+
+	if c.Recurrence.IsDefined() && (!c.StateStore.IsDefined()) {
+		problems.Add("recurrence", "If a reccurrence is defined, a state store must also be defined in order to enforce it")
+	}
+
+	if c.Approval.Required && (!c.StateStore.IsDefined()) {
+		problems.Add("approval", "If approvals are required, a state store must also be defined in order to store pending plans and approvals")
+	}
+
+	if c.Cache.Providers.IsDefined() && (!c.StateStore.IsDefined()) {
+		problems.Add("cache.providers", "If providers cache is defined, a state store must also be defined in order to manage it")
+	}
+
+	if c.ExportOutputs && (!c.StateStore.IsDefined()) {
+		problems.Add("export_outputs", "If outputs are exported, a state store must also be defined in order to store them")
+	}
+
+	if IsStateOperationsCommand(c.Command) && (!c.StateStore.IsDefined()) {
+		problems.Add("command", fmt.Sprintf("The %s command requires a state store in order to remember the operations that were executed", c.Command))
+	}
+
+	problems.AddErr("state_operations", c.StateOperations.Validate())
+
+	if len(c.Replace) > 0 && c.Command == "destroy" {
+		problems.Add("replace", "Resources cannot be replaced by the destroy command")
+	}
+
+	if c.Destroy.Confirmation != "" && (!c.StateStore.IsDefined()) {
+		problems.Add("destroy.confirmation", "If a destroy confirmation is defined, a state store must also be defined in order to store the matching value")
+	}
+
+	if c.Trigger.IsDefined() && (!c.StateStore.IsDefined()) {
+		problems.Add("trigger", "If a trigger is defined, a state store must also be defined in order to track its value")
+	}
+
+	problems.AddErr("trigger", c.Trigger.Validate())
+
+	if c.Daemon.IsDefined() {
+		problems.AddErr("daemon", c.Daemon.Validate())
+	}
+
+	problems.AddErr("recurrence", c.Recurrence.Validate())
+
+	for idx, window := range c.Recurrence.Windows {
+		problems.AddErr(fmt.Sprintf("recurrence.windows[%d]", idx), window.Validate())
+	}
+
+	for idx, blackout := range c.Recurrence.Blackouts {
+		problems.AddErr(fmt.Sprintf("recurrence.blackouts[%d]", idx), blackout.Validate())
+	}
+
+	if c.Recurrence.MaxFailures < 0 {
+		problems.Add("recurrence.max_failures", "The maximum number of failures cannot be negative")
+	}
+
+	if c.Recurrence.RetryBackoff.Max > 0 && c.Recurrence.RetryBackoff.Max < c.Recurrence.RetryBackoff.Initial {
+		problems.Add("recurrence.retry_backoff.max", "The maximum retry delay cannot be lower than the initial one")
+	}
+
+	if c.History.Size < 0 {
+		problems.Add("history.size", "The history size cannot be negative")
+	}
+
+	problems.AddErr("change_budget", c.ChangeBudget.Validate())
+
+	for name, variable := range c.Variables {
+		problems.AddErr(joinYamlPath("variables", name), variable.Validate())
+	}
+
+	for idx, src := range c.Sources {
+		if src.GetType() == source.TypeUndefined {
+			problems.Add(fmt.Sprintf("sources[%d]", idx), "Source could not be properly interpreted. One of dir, repo or backend_http must be defined")
+		}
+	}
+
+	problems = append(problems, findNegativeDurations(reflect.ValueOf(*c), "")...)
+	problems = append(problems, c.validateDurations()...)
+
+	return problems
+}
+
+func checkFile(problems *Problems, path string, file string) {
+	if file == "" {
+		return
+	}
+
+	_, err := os.Stat(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			problems.Add(path, fmt.Sprintf("File \"%s\" does not exist", file))
+			return
+		}
+
+		problems.Add(path, fmt.Sprintf("File \"%s\" cannot be accessed: %s", file, err.Error()))
+	}
+}
+
+func checkAuthFiles(problems *Problems, path string, authConf auth.Auth) {
+	checkFile(problems, joinYamlPath(path, "ca_cert"), authConf.CaCert)
+	checkFile(problems, joinYamlPath(path, "client_cert"), authConf.ClientCert)
+	checkFile(problems, joinYamlPath(path, "client_key"), authConf.ClientKey)
+	checkFile(problems, joinYamlPath(path, "password_auth"), authConf.PasswordAuth)
+}
+
+func checkS3Files(problems *Problems, path string, s3Conf s3.S3ClientConfig) {
+	checkFile(problems, joinYamlPath(path, "auth.ca_cert"), s3Conf.Auth.CaCert)
+	checkFile(problems, joinYamlPath(path, "auth.key_auth"), s3Conf.Auth.KeyAuth)
+}
+
+//Checks that the files referenced by the configuration exist
+func (c *Config) checkFiles() Problems {
+	problems := Problems{}
+
+	if strings.ContainsRune(c.TerraformPath, filepath.Separator) {
+		checkFile(&problems, "terraform_path", c.TerraformPath)
+	} else if c.TerraformPath != "" {
+		_, lookErr := exec.LookPath(c.TerraformPath)
+		if lookErr != nil {
+			problems.Add("terraform_path", fmt.Sprintf("Executable \"%s\" could not be found in the path", c.TerraformPath))
+		}
+	}
+
+	for idx, src := range c.Sources {
+		srcPath := fmt.Sprintf("sources[%d]", idx)
+		checkFile(&problems, joinYamlPath(srcPath, "dir"), src.Dir)
+		checkFile(&problems, joinYamlPath(srcPath, "repo.auth.ssh.ssh_key_path"), src.GitRepo.Auth.Ssh.SshKeyPath)
+		checkFile(&problems, joinYamlPath(srcPath, "repo.auth.ssh.known_hosts_path"), src.GitRepo.Auth.Ssh.KnownHostsPath)
+		checkFile(&problems, joinYamlPath(srcPath, "repo.auth.https.password_auth"), src.GitRepo.Auth.Https.PasswordAuth)
+		for keyIdx, keyPath := range src.GitRepo.GpgPublicKeysPaths {
+			checkFile(&problems, joinYamlPath(srcPath, fmt.Sprintf("repo.gpg_public_keys_paths[%d]", keyIdx)), keyPath)
+		}
+	}
+
+	if c.Command == "migrate_backend" {
+		checkFile(&problems, "backend_migration.next_backend", c.BackendMigration.NextBackend)
+	}
+
+	checkAuthFiles(&problems, "state_store.etcd.auth", c.StateStore.Etcd.Auth)
+	checkS3Files(&problems, "state_store.s3", c.StateStore.S3)
+	checkAuthFiles(&problems, "trigger.etcd.auth", c.Trigger.Etcd.Auth)
+	checkS3Files(&problems, "trigger.s3", c.Trigger.S3)
+	checkS3Files(&problems, "cache.providers.s3", c.Cache.Providers.S3)
+	checkS3Files(&problems, "cache.git_sources.s3", c.Cache.GitSources.S3)
+	checkAuthFiles(&problems, "metrics.collector.prometheus_pushgateway.auth", c.Metrics.Collector.PrometheusPushgateway.Auth)
+	checkAuthFiles(&problems, "metrics.collector.prometheus_remote_write.auth", c.Metrics.Collector.PrometheusRemoteWrite.Auth)
+
+	for idx, imp := range c.ImportOutputs {
+		impPath := fmt.Sprintf("import_outputs[%d].state_store", idx)
+		checkAuthFiles(&problems, joinYamlPath(impPath, "etcd.auth"), imp.StateStore.Etcd.Auth)
+		checkS3Files(&problems, joinYamlPath(impPath, "s3"), imp.StateStore.S3)
+	}
+
+	for name, variable := range c.Variables {
+		checkFile(&problems, joinYamlPath("variables", name+".file"), variable.File)
+	}
+
+	for idx, varFile := range c.VarFiles {
+		checkFile(&problems, fmt.Sprintf("var_files[%d]", idx), varFile)
+	}
+
+	return problems
+}
+
+//Checks that the files referenced by the daemon configuration exist
+func (c *Config) checkDaemonFiles() Problems {
+	problems := Problems{}
+
+	checkFile(&problems, "daemon.server.server_cert", c.Daemon.Server.ServerCert)
+	checkFile(&problems, "daemon.server.server_key", c.Daemon.Server.ServerKey)
+	checkAuthFiles(&problems, "daemon.server.auth", c.Daemon.Server.Auth)
+	checkFile(&problems, "daemon.server.webhook.secret_path", c.Daemon.Server.Webhook.SecretPath)
+
+	return problems
+}
+
+//Validates the configuration file without running anything, including checks that the files it references exist.
+//All the problems found are reported at once.
+func ValidateConfig(overrides Overrides) error {
+	b, readErr := readConfigFile(overrides)
+	if readErr != nil {
+		return readErr
+	}
+
+	c, problems := parseConfig(b, overrides)
+	if c.HasStacks() {
+		for idx, stack := range c.Stacks {
+			problems.Merge(fmt.Sprintf("stacks[%d]", idx), stack.Config.checkFiles())
+		}
+	} else {
+		problems = append(problems, c.checkFiles()...)
+	}
+	problems = append(problems, c.checkDaemonFiles()...)
+
+	return problems.Err()
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/Ferlab-Ste-Justine/terracd/daemon"
+	"github.com/Ferlab-Ste-Justine/terracd/recurrence"
+	"github.com/Ferlab-Ste-Justine/terracd/state"
+	"github.com/Ferlab-Ste-Justine/terracd/trigger"
+)
+
+//Returns the sorted paths of the problems
+func getProblemPaths(problems Problems) []string {
+	paths := []string{}
+	for _, problem := range problems {
+		paths = append(paths, problem.Path)
+	}
+	sort.Strings(paths)
+
+	return paths
+}
+
+func TestFindUnknownFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected []string
+	}{
+		{"known fields", "command: apply\nsources:\n  - dir: /opt/terraform\nstate_store:\n  fs:\n    enabled: true", []string{}},
+		{"misspelled field", "termination_hook:\n  success: {}", []string{"termination_hook"}},
+		{"nested field", "state_store:\n  etcd:\n    endpoint: [127.0.0.1:2379]", []string{"state_store.etcd.endpoint"}},
+		{"field in a list", "sources:\n  - dir: /opt/terraform\n  - dri: /opt/other", []string{"sources[1].dri"}},
+		{"field in a map", "variables:\n  region:\n    valeu: ca-central-1", []string{"variables.region.valeu"}},
+		{"field excluded from parsing", "daemon:\n  server:\n    webhook:\n      secret: value", []string{"daemon.server.webhook.secret"}},
+		{"stack fields", "stacks:\n  - name: network\n    depends_on: [dns]\n    command: apply\n    comand: apply", []string{"stacks[0].comand"}},
+		{"several fields", "comand: apply\ntimeouts:\n  terraform_int: 5m", []string{"comand", "timeouts.terraform_int"}},
+		{"value of an unexpected type", "sources: /opt/terraform", []string{}},
+	}
+
+	for _, test := range tests {
+		content := map[interface{}]interface{}{}
+		err := yaml.Unmarshal([]byte(test.content), &content)
+		if err != nil {
+			t.Errorf("%s", err.Error())
+			continue
+		}
+
+		paths := getProblemPaths(findUnknownFields(content, configType, ""))
+		if !reflect.DeepEqual(paths, test.expected) {
+			t.Errorf("Expected unknown fields with %s to be %v and they were %v", test.name, test.expected, paths)
+		}
+	}
+}
+
+func TestFindNegativeDurations(t *testing.T) {
+	tests := []struct {
+		name     string
+		conf     Config
+		expected []string
+	}{
+		{"no durations", Config{}, []string{}},
+		{"positive durations", Config{RandomJitter: time.Second, Timeouts: ConfigTimeouts{TerraformPlan: time.Minute}}, []string{}},
+		{"negative duration", Config{RandomJitter: -time.Second}, []string{"random_jitter"}},
+		{"negative nested duration", Config{Timeouts: ConfigTimeouts{TerraformPlan: -time.Minute}}, []string{"timeouts.terraform_plan"}},
+		{"negative duration in a list", Config{ImportOutputs: []OutputsImport{OutputsImport{}, OutputsImport{StateStore: state.StateStoreConfig{Etcd: state.EtcdConfig{RequestTimeout: -time.Second}}}}}, []string{"import_outputs[1].state_store.etcd.request_timeout"}},
+		{"several negative durations", Config{StateStore: state.StateStoreConfig{Lock: state.LockConfig{Ttl: -time.Second}}, Daemon: daemon.DaemonConfig{ShutdownTimeout: -time.Second}}, []string{"daemon.shutdown_timeout", "state_store.lock.ttl"}},
+		{"duration excluded from parsing", Config{Stacks: Stacks{Stack{Name: "network", Config: Config{RandomJitter: -time.Second}}}}, []string{}},
+	}
+
+	for _, test := range tests {
+		paths := getProblemPaths(findNegativeDurations(reflect.ValueOf(test.conf), ""))
+		if !reflect.DeepEqual(paths, test.expected) {
+			t.Errorf("Expected negative durations with %s to be %v and they were %v", test.name, test.expected, paths)
+		}
+	}
+}
+
+func TestValidateDurations(t *testing.T) {
+	etcd := state.EtcdConfig{Endpoints: []string{"127.0.0.1:2379"}, RequestTimeout: 10 * time.Second}
+	etcdNoTimeout := state.EtcdConfig{Endpoints: []string{"127.0.0.1:2379"}}
+	server := daemon.ServerConfig{Address: "127.0.0.1:8080"}
+
+	tests := []struct {
+		name     string
+		conf     Config
+		expected []string
+	}{
+		{"no durations", Config{}, []string{}},
+		{"lock ttl under a second", Config{StateStore: state.StateStoreConfig{Lock: state.LockConfig{Ttl: 500 * time.Millisecond}}}, []string{"state_store.lock.ttl"}},
+		{"lock ttl of a second", Config{StateStore: state.StateStoreConfig{Lock: state.LockConfig{Ttl: time.Second}}}, []string{}},
+		{"etcd state store with a request timeout", Config{StateStore: state.StateStoreConfig{Etcd: etcd}}, []string{}},
+		{"etcd state store without a request timeout", Config{StateStore: state.StateStoreConfig{Etcd: etcdNoTimeout}}, []string{"state_store.etcd.request_timeout"}},
+		{"etcd trigger without a request timeout", Config{Trigger: trigger.TriggerConfig{Etcd: etcdNoTimeout}}, []string{"trigger.etcd.request_timeout"}},
+		{"retry backoff without an initial delay", Config{Recurrence: recurrence.Recurrence{RetryBackoff: recurrence.RetryBackoff{Max: time.Hour}}}, []string{"recurrence.retry_backoff.initial"}},
+		{"retry backoff with an initial delay", Config{Recurrence: recurrence.Recurrence{RetryBackoff: recurrence.RetryBackoff{Initial: time.Minute, Max: time.Hour}}}, []string{}},
+		{"daemon interval under a second", Config{Daemon: daemon.DaemonConfig{Interval: 500 * time.Millisecond}}, []string{"daemon.interval"}},
+		{"daemon schedule", Config{Daemon: daemon.DaemonConfig{Schedule: "0 * * * *"}}, []string{}},
+		{"daemon server without an interval or a schedule", Config{Daemon: daemon.DaemonConfig{Server: server}}, []string{"daemon"}},
+		{"daemon shutdown timeout without an interval or a schedule", Config{Daemon: daemon.DaemonConfig{ShutdownTimeout: time.Minute}}, []string{"daemon"}},
+		{"webhook debounce under a second", Config{Daemon: daemon.DaemonConfig{Interval: time.Minute, Server: daemon.ServerConfig{Address: "127.0.0.1:8080", Webhook: daemon.WebhookConfig{Debounce: 500 * time.Millisecond}}}}, []string{"daemon.server.webhook.debounce"}},
+		{"webhook debounce of a second", Config{Daemon: daemon.DaemonConfig{Interval: time.Minute, Server: daemon.ServerConfig{Address: "127.0.0.1:8080", Webhook: daemon.WebhookConfig{Debounce: time.Second}}}}, []string{}},
+	}
+
+	for _, test := range tests {
+		paths := getProblemPaths(test.conf.validateDurations())
+		if !reflect.DeepEqual(paths, test.expected) {
+			t.Errorf("Expected duration problems with %s to be %v and they were %v", test.name, test.expected, paths)
+		}
+	}
+}
+
+func TestProblems(t *testing.T) {
+	problems := Problems{}
+	if problems.Err() != nil {
+		t.Errorf("Expected no problems to result in no error and they didn't")
+	}
+
+	problems.AddErr("command", nil)
+	if len(problems) != 0 {
+		t.Errorf("Expected a nil error not to be added as a problem and it was")
+	}
+
+	problems.Add("sources[0].dir", "File \"/opt/terraform\" does not exist")
+	problems.AddErr("command", errors.New("Unknown command"))
+	problems.Add("", "General problem")
+	problems.Merge("stacks[1]", Problems{Problem{Path: "recurrence", Message: "Nested problem"}, Problem{Path: "[0]", Message: "Indexed problem"}, Problem{Path: "", Message: "Stack problem"}})
+
+	expected := strings.Join([]string{
+		"The configuration has the following problems:",
+		"  - General problem",
+		"  - command: Unknown command",
+		"  - sources[0].dir: File \"/opt/terraform\" does not exist",
+		"  - stacks[1]: Stack problem",
+		"  - stacks[1].recurrence: Nested problem",
+		"  - stacks[1][0]: Indexed problem",
+	}, "\n")
+
+	err := problems.Err()
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected the problems to be reported sorted by path and they weren't: %v", err)
+	}
+}
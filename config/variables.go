@@ -0,0 +1,32 @@
+package config
+
+import (
+	"errors"
+)
+
+type Variable struct {
+	Value interface{}
+	File  string
+	Env   string
+}
+
+func (variable *Variable) Validate() error {
+	definitions := 0
+	if variable.Value != nil {
+		definitions += 1
+	}
+	if variable.File != "" {
+		definitions += 1
+	}
+	if variable.Env != "" {
+		definitions += 1
+	}
+
+	if definitions != 1 {
+		return errors.New("Exactly one of value, file or env must be defined")
+	}
+
+	return nil
+}
+
+type Variables map[string]Variable
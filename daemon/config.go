@@ -0,0 +1,58 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Ferlab-Ste-Justine/terracd/recurrence"
+)
+
+type DaemonConfig struct {
+	Interval        time.Duration
+	Schedule        string
+	Timezone        string
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	Server          ServerConfig
+}
+
+func (conf *DaemonConfig) IsDefined() bool {
+	return conf.Interval > 0 || conf.Schedule != ""
+}
+
+func (conf *DaemonConfig) getLocation() (*time.Location, error) {
+	if conf.Timezone == "" {
+		return time.Local, nil
+	}
+
+	loc, err := time.LoadLocation(conf.Timezone)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Error loading daemon timezone \"%s\": %s", conf.Timezone, err.Error()))
+	}
+
+	return loc, nil
+}
+
+func (conf *DaemonConfig) Validate() error {
+	if conf.Interval > 0 && conf.Schedule != "" {
+		return errors.New("The daemon cannot define both an interval and a schedule")
+	}
+
+	if conf.Schedule != "" {
+		_, schedErr := recurrence.ParseCronSchedule(conf.Schedule)
+		if schedErr != nil {
+			return schedErr
+		}
+	}
+
+	_, locErr := conf.getLocation()
+	if locErr != nil {
+		return locErr
+	}
+
+	if conf.Server.IsDefined() {
+		return conf.Server.Validate()
+	}
+
+	return nil
+}
@@ -0,0 +1,309 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Ferlab-Ste-Justine/terracd/recurrence"
+	"github.com/Ferlab-Ste-Justine/terracd/source"
+	"github.com/Ferlab-Ste-Justine/terracd/trigger"
+)
+
+var (
+	ErrIterationRunning = errors.New("An iteration is already in progress")
+	ErrIterationPending = errors.New("An iteration is already about to start")
+	ErrNoIteration      = errors.New("No iteration is in progress")
+)
+
+type Trigger struct {
+	Command string
+	Force   bool
+	Origin  string
+}
+
+type IterationFn func(ctx context.Context, trigger Trigger) int
+
+type Callbacks struct {
+	Iteration       IterationFn
+	ReadState       func() (interface{}, error)
+	ValidateCommand func(command string) error
+}
+
+type scheduler struct {
+	conf     DaemonConfig
+	cron     recurrence.CronSchedule
+	location *time.Location
+	started  bool
+}
+
+func newScheduler(conf DaemonConfig) (*scheduler, error) {
+	sched := scheduler{conf: conf}
+
+	loc, locErr := conf.getLocation()
+	if locErr != nil {
+		return nil, locErr
+	}
+	sched.location = loc
+
+	if conf.Schedule != "" {
+		cron, cronErr := recurrence.ParseCronSchedule(conf.Schedule)
+		if cronErr != nil {
+			return nil, cronErr
+		}
+		sched.cron = cron
+	}
+
+	return &sched, nil
+}
+
+func (sched *scheduler) next(now time.Time) time.Time {
+	if sched.conf.Schedule != "" {
+		return sched.cron.Next(now.In(sched.location))
+	}
+
+	if !sched.started {
+		sched.started = true
+		return now
+	}
+
+	return now.Add(sched.conf.Interval)
+}
+
+type Status struct {
+	Running       bool       `json:"running"`
+	Command       string     `json:"command,omitempty"`
+	Origin        string     `json:"origin,omitempty"`
+	StartedAt     *time.Time `json:"started_at,omitempty"`
+	NextIteration *time.Time `json:"next_iteration,omitempty"`
+	LastExitCode  *int       `json:"last_exit_code,omitempty"`
+}
+
+type Daemon struct {
+	conf      DaemonConfig
+	command   string
+	sources   source.Sources
+	callbacks Callbacks
+	triggerCh chan Trigger
+	logs      logCapture
+	mutex     sync.Mutex
+	status    Status
+	cancel    context.CancelFunc
+}
+
+func (d *Daemon) GetStatus() Status {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return d.status
+}
+
+func (d *Daemon) setNextIteration(next time.Time) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.status.NextIteration = &next
+}
+
+//Requests an immediate iteration. Fails if an iteration is already in progress or about to start.
+func (d *Daemon) Trigger(trigger Trigger) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.status.Running {
+		return ErrIterationRunning
+	}
+
+	select {
+	case d.triggerCh <- trigger:
+		return nil
+	default:
+		return ErrIterationPending
+	}
+}
+
+//Requests an iteration as soon as possible, after the iteration in progress if there is one.
+//Does nothing if an iteration is already about to start.
+func (d *Daemon) Queue(trigger Trigger) {
+	select {
+	case d.triggerCh <- trigger:
+	default:
+	}
+}
+
+//Cancels the iteration in progress
+func (d *Daemon) Cancel() error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if !d.status.Running {
+		return ErrNoIteration
+	}
+
+	fmt.Println("Info: Cancellation of the iteration in progress was requested.")
+	d.cancel()
+	return nil
+}
+
+func (d *Daemon) startIteration(trigger Trigger) (<-chan int, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	d.mutex.Lock()
+	now := time.Now()
+	command := trigger.Command
+	if command == "" {
+		command = d.command
+	}
+	d.status.Running = true
+	d.status.Command = command
+	d.status.Origin = trigger.Origin
+	d.status.StartedAt = &now
+	d.status.NextIteration = nil
+	d.cancel = cancel
+	d.mutex.Unlock()
+
+	buf := newLogBuffer()
+	d.logs.setBuffer(buf)
+
+	doneCh := make(chan int, 1)
+	go func() {
+		code := d.callbacks.Iteration(ctx, trigger)
+		buf.Close()
+
+		d.mutex.Lock()
+		d.status.Running = false
+		d.status.LastExitCode = &code
+		d.mutex.Unlock()
+
+		doneCh <- code
+	}()
+
+	return doneCh, cancel
+}
+
+func waitForIteration(doneCh <-chan int, cancel context.CancelFunc, sigCh <-chan os.Signal, timeout time.Duration) int {
+	var timeoutCh <-chan time.Time
+	if timeout > 0 {
+		fmt.Printf("Info: Waiting up to %s for the iteration in progress to finish.\n", timeout.String())
+		timeoutCh = time.After(timeout)
+	} else {
+		fmt.Println("Info: Waiting for the iteration in progress to finish.")
+	}
+
+	select {
+	case code := <-doneCh:
+		cancel()
+		return code
+	case <-timeoutCh:
+		fmt.Println("Warning: Iteration in progress did not finish within the shutdown timeout. Cancelling it.")
+	case sig := <-sigCh:
+		fmt.Printf("Warning: Received a second %s signal. Cancelling the iteration in progress.\n", sig.String())
+	}
+
+	cancel()
+	<-doneCh
+	return 1
+}
+
+func (d *Daemon) run(sched *scheduler) int {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGTERM, os.Interrupt)
+	defer signal.Stop(sigCh)
+
+	for {
+		next := sched.next(time.Now())
+		if next.IsZero() {
+			fmt.Println("Error: No upcoming execution could be found for the daemon schedule.")
+			return 1
+		}
+
+		fmt.Printf("Info: Next iteration scheduled at %s.\n", next.Format(time.RFC3339))
+		d.setNextIteration(next)
+		timer := time.NewTimer(time.Until(next))
+
+		trigger := Trigger{Origin: "schedule"}
+		select {
+		case sig := <-sigCh:
+			timer.Stop()
+			fmt.Printf("Info: Received %s signal. Exiting.\n", sig.String())
+			return 0
+		case <-timer.C:
+		case trigger = <-d.triggerCh:
+			timer.Stop()
+			fmt.Printf("Info: Starting iteration requested by %s.\n", trigger.Origin)
+		}
+
+		doneCh, cancel := d.startIteration(trigger)
+
+		select {
+		case code := <-doneCh:
+			cancel()
+			if code != 0 {
+				fmt.Println("Warning: Iteration failed. The daemon will proceed with the next scheduled iteration.")
+			}
+		case sig := <-sigCh:
+			fmt.Printf("Info: Received %s signal during an iteration.\n", sig.String())
+			return waitForIteration(doneCh, cancel, sigCh, d.conf.ShutdownTimeout)
+		}
+	}
+}
+
+//Queues an iteration each time one of the triggers that can be watched is written
+func (d *Daemon) watchTriggers(triggers []trigger.TriggerConfig) {
+	deb := &debouncer{
+		duration: time.Second,
+		fn: func() {
+			d.Queue(Trigger{Origin: "trigger"})
+		},
+	}
+
+	for _, trig := range triggers {
+		if trig.IsWatchable() {
+			go trig.Watch(func() {
+				fmt.Println("Info: The trigger was written.")
+				deb.Call()
+			})
+		}
+	}
+}
+
+func Run(conf DaemonConfig, command string, sources source.Sources, triggers []trigger.TriggerConfig, callbacks Callbacks) int {
+	sched, schedErr := newScheduler(conf)
+	if schedErr != nil {
+		fmt.Println(schedErr.Error())
+		return 1
+	}
+
+	d := &Daemon{
+		conf: conf,
+		command: command,
+		sources: sources,
+		callbacks: callbacks,
+		triggerCh: make(chan Trigger, 1),
+	}
+
+	if conf.Server.IsDefined() {
+		captureErr := d.logs.Start()
+		if captureErr != nil {
+			fmt.Println(captureErr.Error())
+			return 1
+		}
+		defer d.logs.Stop()
+
+		server, serverErr := d.startServer()
+		if serverErr != nil {
+			fmt.Println(serverErr.Error())
+			return 1
+		}
+		defer d.stopServer(server)
+	}
+
+	d.watchTriggers(triggers)
+
+	return d.run(sched)
+}
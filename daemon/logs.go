@@ -0,0 +1,150 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+type logBuffer struct {
+	mutex  sync.Mutex
+	cond   *sync.Cond
+	data   []byte
+	closed bool
+}
+
+func newLogBuffer() *logBuffer {
+	buf := &logBuffer{}
+	buf.cond = sync.NewCond(&buf.mutex)
+	return buf
+}
+
+func (buf *logBuffer) Write(p []byte) (int, error) {
+	buf.mutex.Lock()
+	defer buf.mutex.Unlock()
+
+	buf.data = append(buf.data, p...)
+	buf.cond.Broadcast()
+	return len(p), nil
+}
+
+func (buf *logBuffer) Close() {
+	buf.mutex.Lock()
+	defer buf.mutex.Unlock()
+
+	buf.closed = true
+	buf.cond.Broadcast()
+}
+
+//Returns the logs following the given offset, waiting for more logs if there are none yet.
+//The boolean return value is false once the buffer is closed and all its logs were read or the context is done.
+func (buf *logBuffer) ReadFrom(ctx context.Context, offset int) ([]byte, bool) {
+	stop := context.AfterFunc(ctx, func() {
+		buf.mutex.Lock()
+		defer buf.mutex.Unlock()
+		buf.cond.Broadcast()
+	})
+	defer stop()
+
+	buf.mutex.Lock()
+	defer buf.mutex.Unlock()
+
+	for offset >= len(buf.data) && !buf.closed {
+		if ctx.Err() != nil {
+			return nil, false
+		}
+		buf.cond.Wait()
+	}
+
+	if offset >= len(buf.data) {
+		return nil, false
+	}
+
+	chunk := make([]byte, len(buf.data)-offset)
+	copy(chunk, buf.data[offset:])
+	return chunk, true
+}
+
+type logCapture struct {
+	mutex     sync.Mutex
+	current   *logBuffer
+	stdout    *os.File
+	stderr    *os.File
+	writers   []*os.File
+	forwarded sync.WaitGroup
+}
+
+func (capture *logCapture) getBuffer() *logBuffer {
+	capture.mutex.Lock()
+	defer capture.mutex.Unlock()
+
+	return capture.current
+}
+
+func (capture *logCapture) setBuffer(buf *logBuffer) {
+	capture.mutex.Lock()
+	defer capture.mutex.Unlock()
+
+	capture.current = buf
+}
+
+func (capture *logCapture) forward(reader io.Reader, output io.Writer) {
+	defer capture.forwarded.Done()
+
+	chunk := make([]byte, 4096)
+	for {
+		n, err := reader.Read(chunk)
+		if n > 0 {
+			output.Write(chunk[:n])
+			buf := capture.getBuffer()
+			if buf != nil {
+				buf.Write(chunk[:n])
+			}
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+//Redirects the process stdout and stderr through pipes so that the output of iterations can be served by the http server
+func (capture *logCapture) Start() error {
+	stdoutReader, stdoutWriter, stdoutErr := os.Pipe()
+	if stdoutErr != nil {
+		return errors.New(fmt.Sprintf("Error creating pipe to capture logs: %s", stdoutErr.Error()))
+	}
+
+	stderrReader, stderrWriter, stderrErr := os.Pipe()
+	if stderrErr != nil {
+		return errors.New(fmt.Sprintf("Error creating pipe to capture logs: %s", stderrErr.Error()))
+	}
+
+	capture.stdout = os.Stdout
+	capture.stderr = os.Stderr
+	capture.writers = []*os.File{stdoutWriter, stderrWriter}
+
+	capture.forwarded.Add(2)
+	go capture.forward(stdoutReader, capture.stdout)
+	go capture.forward(stderrReader, capture.stderr)
+
+	os.Stdout = stdoutWriter
+	os.Stderr = stderrWriter
+
+	return nil
+}
+
+//Restores the process stdout and stderr, making sure all captured output was forwarded first
+func (capture *logCapture) Stop() {
+	os.Stdout = capture.stdout
+	os.Stderr = capture.stderr
+
+	for _, writer := range capture.writers {
+		writer.Close()
+	}
+
+	capture.forwarded.Wait()
+}
@@ -0,0 +1,265 @@
+package daemon
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/Ferlab-Ste-Justine/terracd/auth"
+)
+
+type ServerConfig struct {
+	Address    string
+	ServerCert string `yaml:"server_cert"`
+	ServerKey  string `yaml:"server_key"`
+	Auth       auth.Auth
+	Insecure   bool
+	Webhook    WebhookConfig
+}
+
+func (conf *ServerConfig) IsDefined() bool {
+	return conf.Address != ""
+}
+
+func (conf *ServerConfig) HasTls() bool {
+	return conf.ServerCert != ""
+}
+
+func (conf *ServerConfig) HasAuth() bool {
+	return conf.Auth.CaCert != "" || conf.Auth.PasswordAuth != ""
+}
+
+func (conf *ServerConfig) Validate() error {
+	if (conf.ServerCert == "") != (conf.ServerKey == "") {
+		return errors.New("The daemon server must define both a server_cert and a server_key or neither")
+	}
+
+	if conf.HasAuth() && !conf.HasTls() {
+		return errors.New("The daemon server must define a server_cert and a server_key if authentication is used")
+	}
+
+	if !conf.HasAuth() && !conf.Insecure {
+		return errors.New("The daemon server must authenticate its clients with a ca_cert or a password_auth in its auth field, unless insecure is set to true to explicitly allow unauthenticated access")
+	}
+
+	if conf.HasAuth() && conf.Insecure {
+		return errors.New("The daemon server cannot set insecure to true if it authenticates its clients")
+	}
+
+	if conf.Webhook.Debounce < 0 {
+		return errors.New("The daemon server webhook debounce cannot be negative")
+	}
+
+	return nil
+}
+
+func writeJson(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJson(w, status, map[string]string{"error": err.Error()})
+}
+
+func (d *Daemon) authenticate(handler http.HandlerFunc) http.HandlerFunc {
+	serverAuth := d.conf.Server.Auth
+	return func(w http.ResponseWriter, r *http.Request) {
+		if serverAuth.CaCert != "" && (r.TLS == nil || len(r.TLS.PeerCertificates) == 0) {
+			writeError(w, http.StatusUnauthorized, errors.New("A client certificate is required"))
+			return
+		}
+
+		if serverAuth.HasPassword() {
+			username, password, ok := r.BasicAuth()
+			usernameMatch := subtle.ConstantTimeCompare([]byte(username), []byte(serverAuth.Username)) == 1
+			passwordMatch := subtle.ConstantTimeCompare([]byte(password), []byte(serverAuth.Password)) == 1
+			if !ok || !usernameMatch || !passwordMatch {
+				w.Header().Set("WWW-Authenticate", "Basic realm=\"terracd\"")
+				writeError(w, http.StatusUnauthorized, errors.New("Invalid credentials"))
+				return
+			}
+		}
+
+		handler(w, r)
+	}
+}
+
+func (d *Daemon) handleRuns(w http.ResponseWriter, r *http.Request) {
+	trigger := Trigger{
+		Command: r.URL.Query().Get("command"),
+		Force:   r.URL.Query().Get("force") == "true",
+		Origin:  "api",
+	}
+
+	if trigger.Command != "" && d.callbacks.ValidateCommand != nil {
+		cmdErr := d.callbacks.ValidateCommand(trigger.Command)
+		if cmdErr != nil {
+			writeError(w, http.StatusBadRequest, cmdErr)
+			return
+		}
+	}
+
+	triggerErr := d.Trigger(trigger)
+	if triggerErr != nil {
+		writeError(w, http.StatusConflict, triggerErr)
+		return
+	}
+
+	writeJson(w, http.StatusAccepted, d.GetStatus())
+}
+
+func (d *Daemon) handleStatus(w http.ResponseWriter, r *http.Request) {
+	writeJson(w, http.StatusOK, d.GetStatus())
+}
+
+func (d *Daemon) handleState(w http.ResponseWriter, r *http.Request) {
+	st, stErr := d.callbacks.ReadState()
+	if stErr != nil {
+		writeError(w, http.StatusInternalServerError, stErr)
+		return
+	}
+
+	output, marErr := yaml.Marshal(st)
+	if marErr != nil {
+		writeError(w, http.StatusInternalServerError, marErr)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/yaml")
+	w.WriteHeader(http.StatusOK)
+	w.Write(output)
+}
+
+func (d *Daemon) handleLogs(w http.ResponseWriter, r *http.Request) {
+	buf := d.logs.getBuffer()
+	if buf == nil {
+		writeError(w, http.StatusNotFound, errors.New("No iteration was started yet"))
+		return
+	}
+
+	flusher, canFlush := w.(http.Flusher)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	offset := 0
+	for {
+		chunk, more := buf.ReadFrom(r.Context(), offset)
+		if !more {
+			return
+		}
+
+		_, writeErr := w.Write(chunk)
+		if writeErr != nil {
+			return
+		}
+		if canFlush {
+			flusher.Flush()
+		}
+
+		offset += len(chunk)
+	}
+}
+
+func (d *Daemon) handleCancel(w http.ResponseWriter, r *http.Request) {
+	cancelErr := d.Cancel()
+	if cancelErr != nil {
+		writeError(w, http.StatusConflict, cancelErr)
+		return
+	}
+
+	writeJson(w, http.StatusAccepted, d.GetStatus())
+}
+
+func (d *Daemon) startServer() (*http.Server, error) {
+	conf := d.conf.Server
+
+	passErr := conf.Auth.ResolvePassword()
+	if passErr != nil {
+		return nil, passErr
+	}
+	d.conf.Server.Auth = conf.Auth
+
+	if conf.Webhook.IsDefined() {
+		secretErr := conf.Webhook.ResolveSecret()
+		if secretErr != nil {
+			return nil, secretErr
+		}
+		d.conf.Server.Webhook = conf.Webhook
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /runs", d.authenticate(d.handleRuns))
+	mux.HandleFunc("GET /status", d.authenticate(d.handleStatus))
+	mux.HandleFunc("GET /state", d.authenticate(d.handleState))
+	mux.HandleFunc("GET /logs", d.authenticate(d.handleLogs))
+	mux.HandleFunc("POST /cancel", d.authenticate(d.handleCancel))
+	if conf.Webhook.IsDefined() {
+		debounce := conf.Webhook.Debounce
+		if debounce == 0 {
+			debounce = 10 * time.Second
+		}
+
+		deb := &debouncer{
+			duration: debounce,
+			fn: func() {
+				d.Queue(Trigger{Origin: "webhook"})
+			},
+		}
+		mux.HandleFunc("POST /webhook", d.handleWebhook(deb))
+	}
+
+	server := &http.Server{
+		Addr:    conf.Address,
+		Handler: mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	listener, listenErr := net.Listen("tcp", conf.Address)
+	if listenErr != nil {
+		return nil, errors.New(fmt.Sprintf("Error listening on %s: %s", conf.Address, listenErr.Error()))
+	}
+
+	if conf.HasTls() {
+		tlsConf, tlsErr := conf.Auth.GetServerTlsConfigs(conf.ServerCert, conf.ServerKey)
+		if tlsErr != nil {
+			listener.Close()
+			return nil, tlsErr
+		}
+		server.TLSConfig = tlsConf
+	}
+
+	go func() {
+		var serveErr error
+		if conf.HasTls() {
+			serveErr = server.ServeTLS(listener, "", "")
+		} else {
+			serveErr = server.Serve(listener)
+		}
+
+		if serveErr != nil && serveErr != http.ErrServerClosed {
+			fmt.Printf("Error: Http server stopped unexpectedly: %s\n", serveErr.Error())
+		}
+	}()
+
+	fmt.Printf("Info: Http server listening on %s.\n", conf.Address)
+	return server, nil
+}
+
+func (d *Daemon) stopServer(server *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	shutdownErr := server.Shutdown(ctx)
+	if shutdownErr != nil {
+		fmt.Printf("Warning: Failed to gracefully stop the http server: %s\n", shutdownErr.Error())
+	}
+}
@@ -0,0 +1,176 @@
+package daemon
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ferlab-Ste-Justine/terracd/source"
+)
+
+const maxWebhookPayloadSize = 25 * 1024 * 1024
+
+type WebhookConfig struct {
+	SecretPath string        `yaml:"secret_path"`
+	Debounce   time.Duration
+	Secret     string        `yaml:"-"`
+}
+
+func (conf *WebhookConfig) IsDefined() bool {
+	return conf.SecretPath != ""
+}
+
+func (conf *WebhookConfig) ResolveSecret() error {
+	b, err := ioutil.ReadFile(conf.SecretPath)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error reading the webhook secret file: %s", err.Error()))
+	}
+
+	conf.Secret = strings.TrimSpace(string(b))
+	if conf.Secret == "" {
+		return errors.New("The webhook secret file is empty")
+	}
+
+	return nil
+}
+
+type webhookRepository struct {
+	CloneUrl      string `json:"clone_url"`
+	SshUrl        string `json:"ssh_url"`
+	HtmlUrl       string `json:"html_url"`
+	GitHttpUrl    string `json:"git_http_url"`
+	GitSshUrl     string `json:"git_ssh_url"`
+	WebUrl        string `json:"web_url"`
+	DefaultBranch string `json:"default_branch"`
+}
+
+func (repo *webhookRepository) getUrls() []string {
+	return []string{repo.CloneUrl, repo.SshUrl, repo.HtmlUrl, repo.GitHttpUrl, repo.GitSshUrl, repo.WebUrl}
+}
+
+type webhookPushEvent struct {
+	Ref        string
+	Repository webhookRepository
+	Project    webhookRepository
+}
+
+func (event *webhookPushEvent) getGitPush() source.GitPush {
+	defaultBranch := event.Repository.DefaultBranch
+	if defaultBranch == "" {
+		defaultBranch = event.Project.DefaultBranch
+	}
+
+	return source.GitPush{
+		Urls: append(event.Repository.getUrls(), event.Project.getUrls()...),
+		Ref: event.Ref,
+		DefaultBranch: defaultBranch,
+	}
+}
+
+func verifyHmacSignature(payload []byte, secret string, signature string) bool {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(strings.ToLower(signature)))
+}
+
+//Validates the authenticity of the webhook call and returns the kind of event it reports
+func verifyWebhook(r *http.Request, payload []byte, secret string) (string, error) {
+	if event := r.Header.Get("X-Gitlab-Event"); event != "" {
+		token := r.Header.Get("X-Gitlab-Token")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+			return "", errors.New("Invalid gitlab webhook token")
+		}
+
+		if event == "Push Hook" || event == "Tag Push Hook" {
+			return "push", nil
+		}
+		return event, nil
+	}
+
+	if event := r.Header.Get("X-Gitea-Event"); event != "" {
+		if !verifyHmacSignature(payload, secret, r.Header.Get("X-Gitea-Signature")) {
+			return "", errors.New("Invalid gitea webhook signature")
+		}
+
+		return event, nil
+	}
+
+	if event := r.Header.Get("X-GitHub-Event"); event != "" {
+		signature := r.Header.Get("X-Hub-Signature-256")
+		if !strings.HasPrefix(signature, "sha256=") || !verifyHmacSignature(payload, secret, strings.TrimPrefix(signature, "sha256=")) {
+			return "", errors.New("Invalid github webhook signature")
+		}
+
+		return event, nil
+	}
+
+	return "", errors.New("Webhook call does not originate from a supported git provider")
+}
+
+type debouncer struct {
+	mutex    sync.Mutex
+	timer    *time.Timer
+	duration time.Duration
+	fn       func()
+}
+
+//Calls the function once no further calls were requested for the duration of the debounce
+func (deb *debouncer) Call() {
+	deb.mutex.Lock()
+	defer deb.mutex.Unlock()
+
+	if deb.timer != nil {
+		deb.timer.Stop()
+	}
+
+	deb.timer = time.AfterFunc(deb.duration, deb.fn)
+}
+
+func (d *Daemon) handleWebhook(deb *debouncer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		payload, readErr := io.ReadAll(io.LimitReader(r.Body, maxWebhookPayloadSize))
+		if readErr != nil {
+			writeError(w, http.StatusBadRequest, readErr)
+			return
+		}
+
+		event, verifyErr := verifyWebhook(r, payload, d.conf.Server.Webhook.Secret)
+		if verifyErr != nil {
+			fmt.Printf("Warning: Rejected webhook call: %s\n", verifyErr.Error())
+			writeError(w, http.StatusUnauthorized, verifyErr)
+			return
+		}
+
+		if event != "push" {
+			writeJson(w, http.StatusOK, map[string]string{"result": fmt.Sprintf("Ignored %s event", event)})
+			return
+		}
+
+		var pushEvent webhookPushEvent
+		parseErr := json.Unmarshal(payload, &pushEvent)
+		if parseErr != nil {
+			writeError(w, http.StatusBadRequest, errors.New(fmt.Sprintf("Error parsing the push event: %s", parseErr.Error())))
+			return
+		}
+
+		if !d.sources.MatchesGitPush(pushEvent.getGitPush()) {
+			writeJson(w, http.StatusOK, map[string]string{"result": "Push does not match any git source"})
+			return
+		}
+
+		fmt.Printf("Info: Received push on ref %s matching a git source. Scheduling an iteration.\n", pushEvent.Ref)
+		deb.Call()
+		writeJson(w, http.StatusAccepted, map[string]string{"result": "Iteration scheduled"})
+	}
+}
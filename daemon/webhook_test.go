@@ -0,0 +1,104 @@
+package daemon
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testWebhookSecret = "webhook-secret"
+
+func signPayload(payload []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyWebhook(t *testing.T) {
+	payload := []byte(`{"ref":"refs/heads/main"}`)
+	validSignature := signPayload(payload, testWebhookSecret)
+	otherSignature := signPayload(payload, "other-secret")
+
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		event    string
+		accepted bool
+	}{
+		{"github valid signature", map[string]string{"X-GitHub-Event": "push", "X-Hub-Signature-256": "sha256=" + validSignature}, "push", true},
+		{"github uppercase signature", map[string]string{"X-GitHub-Event": "push", "X-Hub-Signature-256": "sha256=" + string(bytes.ToUpper([]byte(validSignature)))}, "push", true},
+		{"github signature of another secret", map[string]string{"X-GitHub-Event": "push", "X-Hub-Signature-256": "sha256=" + otherSignature}, "", false},
+		{"github signature without prefix", map[string]string{"X-GitHub-Event": "push", "X-Hub-Signature-256": validSignature}, "", false},
+		{"github missing signature", map[string]string{"X-GitHub-Event": "push"}, "", false},
+		{"github sha1 signature only", map[string]string{"X-GitHub-Event": "push", "X-Hub-Signature": "sha1=" + validSignature}, "", false},
+		{"github other event", map[string]string{"X-GitHub-Event": "ping", "X-Hub-Signature-256": "sha256=" + validSignature}, "ping", true},
+		{"gitea valid signature", map[string]string{"X-Gitea-Event": "push", "X-Gitea-Signature": validSignature}, "push", true},
+		{"gitea signature of another secret", map[string]string{"X-Gitea-Event": "push", "X-Gitea-Signature": otherSignature}, "", false},
+		{"gitea missing signature", map[string]string{"X-Gitea-Event": "push"}, "", false},
+		{"gitlab valid token", map[string]string{"X-Gitlab-Event": "Push Hook", "X-Gitlab-Token": testWebhookSecret}, "push", true},
+		{"gitlab tag push", map[string]string{"X-Gitlab-Event": "Tag Push Hook", "X-Gitlab-Token": testWebhookSecret}, "push", true},
+		{"gitlab invalid token", map[string]string{"X-Gitlab-Event": "Push Hook", "X-Gitlab-Token": "other-secret"}, "", false},
+		{"gitlab missing token", map[string]string{"X-Gitlab-Event": "Push Hook"}, "", false},
+		{"unknown provider", map[string]string{"X-Hub-Signature-256": "sha256=" + validSignature}, "", false},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest("POST", "/webhook", bytes.NewReader(payload))
+		for key, value := range test.headers {
+			req.Header.Set(key, value)
+		}
+
+		event, err := verifyWebhook(req, payload, testWebhookSecret)
+		if test.accepted && err != nil {
+			t.Errorf("Expected webhook call with %s to be accepted and it wasn't: %s", test.name, err.Error())
+			continue
+		}
+		if !test.accepted && err == nil {
+			t.Errorf("Expected webhook call with %s to be rejected and it wasn't", test.name)
+			continue
+		}
+		if event != test.event {
+			t.Errorf("Expected webhook call with %s to report event \"%s\" and it reported \"%s\"", test.name, test.event, event)
+		}
+	}
+}
+
+func TestVerifyWebhookTamperedPayload(t *testing.T) {
+	payload := []byte(`{"ref":"refs/heads/main"}`)
+	signature := signPayload(payload, testWebhookSecret)
+	tampered := []byte(`{"ref":"refs/heads/other"}`)
+
+	req := httptest.NewRequest("POST", "/webhook", bytes.NewReader(tampered))
+	req.Header.Set("X-GitHub-Event", "push")
+	req.Header.Set("X-Hub-Signature-256", "sha256=" + signature)
+
+	_, err := verifyWebhook(req, tampered, testWebhookSecret)
+	if err == nil {
+		t.Errorf("Expected webhook call with a payload not matching its signature to be rejected and it wasn't")
+	}
+}
+
+func TestDebouncer(t *testing.T) {
+	var calls int64
+	deb := &debouncer{
+		duration: 50 * time.Millisecond,
+		fn: func() {
+			atomic.AddInt64(&calls, 1)
+		},
+	}
+
+	for idx := 0; idx < 5; idx++ {
+		deb.Call()
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	if atomic.LoadInt64(&calls) != 1 {
+		t.Errorf("Expected a burst of calls to result in a single call and it resulted in %d", atomic.LoadInt64(&calls))
+	}
+}
@@ -10,7 +10,9 @@ import (
 	git "github.com/Ferlab-Ste-Justine/git-sdk"
 	gittest "github.com/Ferlab-Ste-Justine/git-sdk/testutils"
 
+	"github.com/Ferlab-Ste-Justine/terracd/auth"
 	"github.com/Ferlab-Ste-Justine/terracd/fs"
+	"github.com/Ferlab-Ste-Justine/terracd/state"
 )
 
 func TestPlanSuccessFailureSkip(t *testing.T) {
@@ -46,7 +48,7 @@ func TestPlanSuccessFailureSkip(t *testing.T) {
 		return
 	}
 	
-	MainNoExit()
+	MainNoExit([]string{})
 	
 	hooks, hooksErr := GetTestHooks()
 	if hooksErr != nil {
@@ -59,7 +61,7 @@ func TestPlanSuccessFailureSkip(t *testing.T) {
 		return
 	}
 
-	MainNoExit()
+	MainNoExit([]string{})
 	hooks2, hooks2Err := GetTestHooks()
 	if hooks2Err != nil {
 		t.Errorf("%s", hooks2Err.Error())
@@ -78,7 +80,7 @@ func TestPlanSuccessFailureSkip(t *testing.T) {
 		return
 	}
 
-	MainNoExit()
+	MainNoExit([]string{})
 	hooks3, hooks3Err := GetTestHooks()
 	if hooks3Err != nil {
 		t.Errorf("%s", hooks3Err.Error())
@@ -100,7 +102,7 @@ func TestPlanSuccessFailureSkip(t *testing.T) {
 		return
 	}
 
-	MainNoExit()
+	MainNoExit([]string{})
 	hooks4, hooks4Err := GetTestHooks()
 	if hooks4Err != nil {
 		t.Errorf("%s", hooks4Err.Error())
@@ -146,7 +148,7 @@ func TestApplySuccessFailureSkip(t *testing.T) {
 		return
 	}
 	
-	MainNoExit()
+	MainNoExit([]string{})
 	
 	hooks, hooksErr := GetTestHooks()
 	if hooksErr != nil {
@@ -181,7 +183,7 @@ func TestApplySuccessFailureSkip(t *testing.T) {
 		return
 	}
 
-	MainNoExit()
+	MainNoExit([]string{})
 	hooks2, hooks2Err := GetTestHooks()
 	if hooks2Err != nil {
 		t.Errorf("%s", hooks2Err.Error())
@@ -205,7 +207,7 @@ func TestApplySuccessFailureSkip(t *testing.T) {
 		return
 	}
 
-	MainNoExit()
+	MainNoExit([]string{})
 	hooks3, hooks3Err := GetTestHooks()
 	if hooks3Err != nil {
 		t.Errorf("%s", hooks3Err.Error())
@@ -249,7 +251,7 @@ func TestApplySuccessFailureSkip(t *testing.T) {
 		return
 	}
 
-	MainNoExit()
+	MainNoExit([]string{})
 	hooks4, hooks4Err := GetTestHooks()
 	if hooks4Err != nil {
 		t.Errorf("%s", hooks4Err.Error())
@@ -262,6 +264,63 @@ func TestApplySuccessFailureSkip(t *testing.T) {
 	}
 }
 
+func TestApplyAwaitingApproval(t *testing.T) {
+	tpl := TestConfTemplate{
+		Command: "apply",
+		MinInterval: "60s",
+		Jitter: "2s",
+		ApprovalRequired: true,
+		State: TestConfTemplateState{
+			Type: "Fs",
+		},
+		DirSources: []TestConfTemplateDirSrc{
+			TestConfTemplateDirSrc{Dir: path.Join("e2e_test", "tf", "fileValA")},
+			TestConfTemplateDirSrc{Dir: path.Join("e2e_test", "tf", "version")},
+		},
+	}
+	defer func() {
+		err := CleanupTestExecution(tpl)
+		if err != nil {
+			t.Errorf("%s", err.Error())
+		}
+	}()
+
+	err := tpl.SetTfPath()
+	if err != nil {
+		t.Errorf("%s", err.Error())
+		return
+	}
+
+	err = tpl.GenerateConfig()
+	if err != nil {
+		t.Errorf("%s", err.Error())
+		return
+	}
+
+	MainNoExit([]string{})
+
+	hooks, hooksErr := GetTestHooks()
+	if hooksErr != nil {
+		t.Errorf("%s", hooksErr.Error())
+		return
+	}
+
+	if hooks.Success != time.Duration(0) || hooks.Skip == time.Duration(0) || hooks.Failure != time.Duration(0) {
+		t.Errorf("Expected apply saving a plan awaiting approval to be skipped and it wasn't")
+		return
+	}
+
+	fileExists, fileExistsErr := fs.PathExists(path.Join("e2e_test", "runtime", "output", "file"))
+	if fileExistsErr != nil {
+		t.Errorf("%s", fileExistsErr.Error())
+		return
+	}
+
+	if fileExists {
+		t.Errorf("Expected plan awaiting approval not to be applied and it was")
+	}
+}
+
 func TestDestroySuccessFailureSkip(t *testing.T) {
 	tpl := TestConfTemplate{
 		Command: "apply",
@@ -295,7 +354,7 @@ func TestDestroySuccessFailureSkip(t *testing.T) {
 		return
 	}
 	
-	MainNoExit()
+	MainNoExit([]string{})
 	
 	hooks, hooksErr := GetTestHooks()
 	if hooksErr != nil {
@@ -331,6 +390,7 @@ func TestDestroySuccessFailureSkip(t *testing.T) {
 	}
 
 	tpl.Command = "destroy"
+	tpl.DirSources = append(tpl.DirSources, TestConfTemplateDirSrc{Dir: path.Join("e2e_test", "tf", "destroyConfirmation")})
 
 	err = tpl.GenerateConfig()
 	if err != nil {
@@ -338,7 +398,7 @@ func TestDestroySuccessFailureSkip(t *testing.T) {
 		return
 	}
 	
-	MainNoExit()
+	MainNoExit([]string{})
 
 	hooks2, hooks2Err := GetTestHooks()
 	if hooks2Err != nil {
@@ -380,7 +440,7 @@ func TestDestroySuccessFailureSkip(t *testing.T) {
 		return
 	}
 	
-	MainNoExit()
+	MainNoExit([]string{})
 
 	hooks3, hooks3Err := GetTestHooks()
 	if hooks3Err != nil {
@@ -400,7 +460,7 @@ func TestDestroySuccessFailureSkip(t *testing.T) {
 		return
 	}
 
-	MainNoExit()
+	MainNoExit([]string{})
 
 	hooks4, hooks4Err := GetTestHooks()
 	if hooks4Err != nil {
@@ -417,6 +477,7 @@ func TestDestroySuccessFailureSkip(t *testing.T) {
 	tpl.DirSources = []TestConfTemplateDirSrc{
 		TestConfTemplateDirSrc{Dir: path.Join("e2e_test", "tf", "fileBadSyntax")},
 		TestConfTemplateDirSrc{Dir: path.Join("e2e_test", "tf", "version")},
+		TestConfTemplateDirSrc{Dir: path.Join("e2e_test", "tf", "destroyConfirmation")},
 	}
 	err = tpl.GenerateConfig()
 	if err != nil {
@@ -424,7 +485,7 @@ func TestDestroySuccessFailureSkip(t *testing.T) {
 		return
 	}
 
-	MainNoExit()
+	MainNoExit([]string{})
 
 	hooks5, hooks5Err := GetTestHooks()
 	if hooks5Err != nil {
@@ -484,7 +545,7 @@ func TestEtcdState(t *testing.T) {
 		return
 	}
 	
-	MainNoExit()
+	MainNoExit([]string{})
 	
 	hooks, hooksErr := GetTestHooks()
 	if hooksErr != nil {
@@ -519,7 +580,7 @@ func TestEtcdState(t *testing.T) {
 		return
 	}
 
-	MainNoExit()
+	MainNoExit([]string{})
 	hooks2, hooks2Err := GetTestHooks()
 	if hooks2Err != nil {
 		t.Errorf("%s", hooks2Err.Error())
@@ -543,7 +604,7 @@ func TestEtcdState(t *testing.T) {
 		return
 	}
 
-	MainNoExit()
+	MainNoExit([]string{})
 	hooks3, hooks3Err := GetTestHooks()
 	if hooks3Err != nil {
 		t.Errorf("%s", hooks3Err.Error())
@@ -587,7 +648,7 @@ func TestEtcdState(t *testing.T) {
 		return
 	}
 
-	MainNoExit()
+	MainNoExit([]string{})
 	hooks4, hooks4Err := GetTestHooks()
 	if hooks4Err != nil {
 		t.Errorf("%s", hooks4Err.Error())
@@ -600,6 +661,118 @@ func TestEtcdState(t *testing.T) {
 	}
 }
 
+func getTestEtcdStateStore() (*state.EtcdStateStore, error) {
+	store := &state.EtcdStateStore{
+		Config: state.EtcdConfig{
+			Prefix: "/state/",
+			Endpoints: []string{"127.0.0.1:3379", "127.0.0.2:3379", "127.0.0.3:3379"},
+			ConnectionTimeout: 10 * time.Second,
+			RequestTimeout: 10 * time.Second,
+			RetryInterval: 10 * time.Second,
+			Retries: 3,
+			Auth: auth.Auth{
+				CaCert: path.Join("e2e_test", "etcd-dependencies", "certs", "ca.crt"),
+				ClientCert: path.Join("e2e_test", "etcd-dependencies", "certs", "root.pem"),
+				ClientKey: path.Join("e2e_test", "etcd-dependencies", "certs", "root.key"),
+			},
+		},
+	}
+
+	return store, store.Initialize()
+}
+
+func TestEtcdStateLockLost(t *testing.T) {
+	tearDown, launchErr := etcdtest.LaunchTestEtcdCluster(path.Join("e2e_test", "etcd-dependencies"), etcdtest.EtcdTestClusterOpts{})
+	if launchErr != nil {
+		t.Errorf("Error occured launching test etcd cluster: %s", launchErr.Error())
+		return
+	}
+
+	defer func() {
+		errs := tearDown()
+		if len(errs) > 0 {
+			t.Errorf("Errors occured tearing down etcd cluster: %s", errs[0].Error())
+		}
+	}()
+
+	first, firstErr := getTestEtcdStateStore()
+	if firstErr != nil {
+		t.Errorf("%s", firstErr.Error())
+		return
+	}
+	defer first.Cleanup()
+
+	second, secondErr := getTestEtcdStateStore()
+	if secondErr != nil {
+		t.Errorf("%s", secondErr.Error())
+		return
+	}
+	defer second.Cleanup()
+
+	lockConf := state.LockConfig{Ttl: 10 * time.Second, Timeout: 5 * time.Second}
+	lost := make(chan struct{}, 1)
+	err := first.Lock(lockConf, func() {
+		lost <- struct{}{}
+	})
+	if err != nil {
+		t.Errorf("%s", err.Error())
+		return
+	}
+
+	//The lease of the first execution is revoked from under it and the lock is then acquired by the second execution
+	err = second.ForceUnlock()
+	if err != nil {
+		t.Errorf("%s", err.Error())
+		return
+	}
+
+	select {
+	case <-lost:
+	case <-time.After(30 * time.Second):
+		t.Errorf("Expected the first execution to be notified of the loss of its lock and it wasn't")
+		return
+	}
+
+	err = second.Lock(lockConf, func() {})
+	if err != nil {
+		t.Errorf("%s", err.Error())
+		return
+	}
+
+	err = first.Unlock()
+	if err != nil {
+		t.Errorf("%s", err.Error())
+		return
+	}
+
+	_, found, readErr := second.ReadObject("lock")
+	if readErr != nil {
+		t.Errorf("%s", readErr.Error())
+		return
+	}
+
+	if !found {
+		t.Errorf("Expected the lock of the second execution to survive the unlock of the first execution and it didn't")
+		return
+	}
+
+	err = second.Unlock()
+	if err != nil {
+		t.Errorf("%s", err.Error())
+		return
+	}
+
+	_, found, readErr = second.ReadObject("lock")
+	if readErr != nil {
+		t.Errorf("%s", readErr.Error())
+		return
+	}
+
+	if found {
+		t.Errorf("Expected the lock of the second execution to be released by its unlock and it wasn't")
+	}
+}
+
 func TestGitApplyRecurrence(t *testing.T) {
     sshPub, sshPubErr := os.ReadFile(path.Join("e2e_test", "git-dependencies", "keys", "ssh", "id_rsa.pub"))
 	if sshPubErr != nil {
@@ -711,7 +884,7 @@ func TestGitApplyRecurrence(t *testing.T) {
 		return
 	}
 	
-	MainNoExit()
+	MainNoExit([]string{})
 	
 	hooks, hooksErr := GetTestHooks()
 	if hooksErr != nil {
@@ -757,7 +930,7 @@ func TestGitApplyRecurrence(t *testing.T) {
 		return
 	}
 
-	MainNoExit()
+	MainNoExit([]string{})
 	hooks2, hooks2Err := GetTestHooks()
 	if hooks2Err != nil {
 		t.Errorf("%s", hooks2Err.Error())
@@ -775,7 +948,7 @@ func TestGitApplyRecurrence(t *testing.T) {
 		return
 	}
 
-	MainNoExit()
+	MainNoExit([]string{})
 	hooks3, hooks3Err := GetTestHooks()
 	if hooks3Err != nil {
 		t.Errorf("%s", hooks3Err.Error())
@@ -797,4 +970,147 @@ func TestGitApplyRecurrence(t *testing.T) {
 		t.Errorf("After third iteration, expected git file to have a value of 'test2' after apply and it didn't")
 		return
 	}	
-}
\ No newline at end of file
+}
+
+func TestDriftDetection(t *testing.T) {
+	tpl := TestConfTemplate{
+		Command: "apply",
+		MinInterval: "1ms",
+		Jitter: "1ms",
+		State: TestConfTemplateState{
+			Type: "Fs",
+		},
+		DirSources: []TestConfTemplateDirSrc{
+			TestConfTemplateDirSrc{Dir: path.Join("e2e_test", "tf", "fileValA")},
+			TestConfTemplateDirSrc{Dir: path.Join("e2e_test", "tf", "version")},
+		},
+	}
+	defer func() {
+		err := CleanupTestExecution(tpl)
+		if err != nil {
+			t.Errorf("%s", err.Error())
+		}
+	}()
+
+	err := tpl.SetTfPath()
+	if err != nil {
+		t.Errorf("%s", err.Error())
+		return
+	}
+
+	err = tpl.GenerateConfig()
+	if err != nil {
+		t.Errorf("%s", err.Error())
+		return
+	}
+
+	MainNoExit([]string{})
+
+	hooks, hooksErr := GetTestHooks()
+	if hooksErr != nil {
+		t.Errorf("%s", hooksErr.Error())
+		return
+	}
+
+	if hooks.Success == time.Duration(0) || hooks.Failure != time.Duration(0) {
+		t.Errorf("Expected apply to succeed and it didn't")
+		return
+	}
+
+	tpl.Command = "drift"
+	err = tpl.GenerateConfig()
+	if err != nil {
+		t.Errorf("%s", err.Error())
+		return
+	}
+
+	MainNoExit([]string{})
+
+	hasVal, hasValErr := FileHasValue("drift", "in_sync")
+	if hasValErr != nil {
+		t.Errorf("%s", hasValErr.Error())
+		return
+	}
+
+	if !hasVal {
+		t.Errorf("Expected drift detection to find the stack in sync after apply and it didn't")
+		return
+	}
+
+	tpl.DirSources = []TestConfTemplateDirSrc{
+		TestConfTemplateDirSrc{Dir: path.Join("e2e_test", "tf", "fileValA")},
+		TestConfTemplateDirSrc{Dir: path.Join("e2e_test", "tf", "outputVal")},
+		TestConfTemplateDirSrc{Dir: path.Join("e2e_test", "tf", "version")},
+	}
+	err = tpl.GenerateConfig()
+	if err != nil {
+		t.Errorf("%s", err.Error())
+		return
+	}
+
+	MainNoExit([]string{})
+
+	hasVal, hasValErr = FileHasValue("drift", "code_changes_pending")
+	if hasValErr != nil {
+		t.Errorf("%s", hasValErr.Error())
+		return
+	}
+
+	if !hasVal {
+		t.Errorf("Expected drift detection to find pending code changes after adding an output and it didn't")
+		return
+	}
+
+	tpl.DirSources = []TestConfTemplateDirSrc{
+		TestConfTemplateDirSrc{Dir: path.Join("e2e_test", "tf", "fileValB")},
+		TestConfTemplateDirSrc{Dir: path.Join("e2e_test", "tf", "version")},
+	}
+	err = tpl.GenerateConfig()
+	if err != nil {
+		t.Errorf("%s", err.Error())
+		return
+	}
+
+	MainNoExit([]string{})
+
+	hasVal, hasValErr = FileHasValue("drift", "code_changes_pending")
+	if hasValErr != nil {
+		t.Errorf("%s", hasValErr.Error())
+		return
+	}
+
+	if !hasVal {
+		t.Errorf("Expected drift detection to find pending code changes after changing the sources and it didn't")
+		return
+	}
+
+	hasVal, hasValErr = FileHasValue(path.Join("e2e_test", "runtime", "output", "file"), "A")
+	if hasValErr != nil {
+		t.Errorf("%s", hasValErr.Error())
+		return
+	}
+
+	if !hasVal {
+		t.Errorf("Expected drift detection to leave the file untouched and it didn't")
+		return
+	}
+
+	err = os.Remove(path.Join("e2e_test", "runtime", "output", "file"))
+	if err != nil {
+		t.Errorf("%s", err.Error())
+		return
+	}
+
+	MainNoExit([]string{})
+
+	hasVal, hasValErr = FileHasValue("drift", "out_of_band_drift")
+	if hasValErr != nil {
+		t.Errorf("%s", hasValErr.Error())
+		return
+	}
+
+	if !hasVal {
+		t.Errorf("Expected drift detection to find out-of-band drift after deleting the file and it didn't")
+		return
+	}
+}
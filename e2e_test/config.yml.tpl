@@ -35,8 +35,10 @@ state_store:
       client_cert: "e2e_test/etcd-dependencies/certs/root.pem"
       client_key: "e2e_test/etcd-dependencies/certs/root.key"
 {{- end}}
-cache:
-  versions_file: "version.tf"
+{{- if .ApprovalRequired }}
+approval:
+  required: true
+{{- end}}
 random_jitter: "{{ .Jitter }}"
 recurrence:
   min_interval: "{{ .MinInterval }}"
@@ -1,3 +1,7 @@
 #!/bin/bash
 
-date +%s%N | cut -b1-13 > $1
\ No newline at end of file
+date +%s%N | cut -b1-13 > $1
+
+if [ -n "$TERRACD_DRIFT" ]; then
+  echo -n "$TERRACD_DRIFT" > drift
+fi
\ No newline at end of file
@@ -0,0 +1,3 @@
+output "value" {
+  value = "A"
+}
\ No newline at end of file
@@ -31,13 +31,14 @@ type TestConfTemplateState struct {
 }
 
 type TestConfTemplate struct {
-	TerraformPath string
-	Command       string
-	MinInterval   string
-	Jitter        string
-	State         TestConfTemplateState
-	DirSources    []TestConfTemplateDirSrc
-	GitSources    []TestConfTemplateGitSrc
+	TerraformPath    string
+	Command          string
+	MinInterval      string
+	Jitter           string
+	ApprovalRequired bool
+	State            TestConfTemplateState
+	DirSources       []TestConfTemplateDirSrc
+	GitSources       []TestConfTemplateGitSrc
 }
 
 func (tpl *TestConfTemplate) SetTfPath() error {
@@ -134,6 +135,11 @@ func CleanupTestHooks() error {
 		return err
 	}
 
+	err = fs.EnsureFileNotExists("drift")
+	if err != nil {
+		return err
+	}
+
 	return fs.EnsureFileNotExists("success")
 }
 
@@ -20,6 +20,8 @@ type Paths struct {
 	FsStore         string
 	ProviderCache   string
 	Work            string
+	Generated       string
+	PlanSummary     string
 }
 
 func GetPaths(rootDir string, dataPath string) Paths {
@@ -36,6 +38,8 @@ func GetPaths(rootDir string, dataPath string) Paths {
 		FsStore: path.Join(dataDir, "fs-store"),
 		ProviderCache: path.Join(dataDir, "provider-cache"),
 		Work: path.Join(rootDir, "work"),
+		Generated: path.Join(rootDir, "generated"),
+		PlanSummary: path.Join(dataDir, "plan-summary.json"),
 	}
 }
 
@@ -55,6 +59,56 @@ func GetFileSha256(src string) (string, error) {
 	return fmt.Sprintf("%x", hash.Sum(nil)), nil
 }
 
+func hashDir(hash io.Writer, dir string, relDir string) error {
+	elems, readDirErr := ioutil.ReadDir(dir)
+	if readDirErr != nil {
+		return readDirErr
+	}
+
+	for _, elem := range elems {
+		if elem.Name() == ".git" {
+			continue
+		}
+
+		src := path.Join(dir, elem.Name())
+		rel := path.Join(relDir, elem.Name())
+
+		srcInfo, err := os.Stat(src)
+		if err != nil {
+			return err
+		}
+
+		if srcInfo.IsDir() {
+			err := hashDir(hash, src, rel)
+			if err != nil {
+				return err
+			}
+		} else {
+			fileHash, err := GetFileSha256(src)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(hash, "%s\x00%s\n", rel, fileHash)
+		}
+	}
+
+	return nil
+}
+
+//Returns a sha256 fingerprint of the names and content of the files in the directories, following symlinks and ignoring .git directories
+func GetDirsSha256(dirs []string) (string, error) {
+	hash := sha256.New()
+	for idx, dir := range dirs {
+		fmt.Fprintf(hash, "%d\n", idx)
+		err := hashDir(hash, dir, "")
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
 func EnsureContainingDirExists(path string) error {
 	dir := filepath.Dir(path)
 	
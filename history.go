@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Ferlab-Ste-Justine/terracd/cmd"
+	"github.com/Ferlab-Ste-Justine/terracd/config"
+	"github.com/Ferlab-Ste-Justine/terracd/fs"
+	"github.com/Ferlab-Ste-Justine/terracd/hook"
+	"github.com/Ferlab-Ste-Justine/terracd/state"
+)
+
+func getRun(conf config.Config, info cmd.RunInfo, startedAt time.Time, err error) state.Run {
+	result := hook.OpSuccess
+	if err != nil {
+		result = hook.OpFailure
+	} else if info.Skipped {
+		result = hook.OpSkip
+	}
+
+	run := state.NewRun(conf.Command, result.ToString(), startedAt, err)
+	run.CommitHashes = info.CommitHashes
+	run.Targets = conf.Targets
+	run.Replace = conf.Replace
+	if info.PlanSummary != nil {
+		run.PlanChanges = info.PlanSummary.Totals.ToMap()
+	}
+	if info.Drift != cmd.DriftUndefined {
+		run.Drift = info.Drift.ToString()
+	}
+
+	return run
+}
+
+func printRun(run state.Run) {
+	fmt.Printf("%s  %-15s %-8s %s\n", run.StartedAt.Local().Format(time.RFC3339), run.Command, run.Result, run.GetDuration().String())
+
+	for _, hash := range run.CommitHashes {
+		fmt.Printf("    commit: %s (%s) %s\n", hash.Url, hash.Ref, hash.Hash)
+	}
+
+	if len(run.Targets) > 0 {
+		fmt.Printf("    targets: %s\n", strings.Join(run.Targets, ", "))
+	}
+
+	if len(run.Replace) > 0 {
+		fmt.Printf("    replace: %s\n", strings.Join(run.Replace, ", "))
+	}
+
+	if len(run.PlanChanges) > 0 {
+		actions := []string{}
+		for action, _ := range run.PlanChanges {
+			actions = append(actions, action)
+		}
+		sort.Strings(actions)
+
+		changes := []string{}
+		for _, action := range actions {
+			changes = append(changes, fmt.Sprintf("%s=%d", action, run.PlanChanges[action]))
+		}
+		fmt.Printf("    plan: %s\n", strings.Join(changes, ", "))
+	}
+
+	if run.Drift != "" {
+		fmt.Printf("    drift: %s\n", run.Drift)
+	}
+
+	if run.Error != "" {
+		fmt.Printf("    error: %s\n", strings.ReplaceAll(run.Error, "\n", "\n           "))
+	}
+}
+
+func printHistory(conf config.Config) error {
+	st, stErr := state.ReadState(conf.StateStore, fs.GetPaths(conf.WorkingDirectory, conf.DataPath))
+	if stErr != nil {
+		return stErr
+	}
+
+	if len(st.History) == 0 {
+		fmt.Println("No runs recorded.")
+		return nil
+	}
+
+	for idx := len(st.History) - 1; idx >= 0; idx-- {
+		printRun(st.History[idx])
+	}
+
+	return nil
+}
+
+//Prints the runs recorded in the state, most recent first
+func history(conf config.Config) int {
+	if !conf.HasStacks() {
+		err := printHistory(conf)
+		if err != nil {
+			fmt.Println(err.Error())
+			return 1
+		}
+
+		return 0
+	}
+
+	code := 0
+	for idx, stack := range conf.Stacks {
+		if idx > 0 {
+			fmt.Println()
+		}
+
+		fmt.Printf("Stack %s:\n", stack.Name)
+		err := printHistory(stack.Config)
+		if err != nil {
+			fmt.Println(err.Error())
+			code = 1
+		}
+	}
+
+	return code
+}
@@ -1,9 +1,13 @@
 package hook
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
 	"os/exec"
+	"sort"
 	"strings"
 )
 
@@ -28,6 +32,23 @@ func (result OpResult) ToString() string {
 	}
 }
 
+type OpInfo map[string]string
+
+func (info OpInfo) GetEnv() []string {
+	keys := []string{}
+	for key, _ := range info {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	env := []string{}
+	for _, key := range keys {
+		env = append(env, fmt.Sprintf("TERRACD_%s=%s", strings.ToUpper(key), info[key]))
+	}
+
+	return env
+}
+
 type TerminationHooks struct {
 	Success TerminationHook
 	Failure TerminationHook
@@ -35,15 +56,15 @@ type TerminationHooks struct {
 	Always  TerminationHook
 }
 
-func (hooks *TerminationHooks) Run(result OpResult) error {
+func (hooks *TerminationHooks) Run(result OpResult, info OpInfo) error {
 	if hooks.Always.IsDefined() {
-		return hooks.Always.Run()
+		return hooks.Always.Run(info)
 	} else if result == OpSuccess && hooks.Success.IsDefined() {
-		return hooks.Success.Run()
+		return hooks.Success.Run(info)
 	} else if result == OpFailure && hooks.Failure.IsDefined() {
-		return hooks.Failure.Run()
+		return hooks.Failure.Run(info)
 	} else if result == OpSkip && hooks.Skip.IsDefined() {
-		return hooks.Skip.Run()
+		return hooks.Skip.Run(info)
 	}
 
 	return nil
@@ -58,12 +79,12 @@ func (hook *TerminationHook) IsDefined() bool {
 	return hook.Command.Command != "" || hook.HttpCall.Method != ""
 }
 
-func (hook *TerminationHook) Run() error {
+func (hook *TerminationHook) Run(info OpInfo) error {
 	if hook.Command.Command != "" {
-		return hook.Command.Run()
+		return hook.Command.Run(info)
 	}
 
-	return hook.HttpCall.Run()
+	return hook.HttpCall.Run(info)
 }
 
 type TerminationHookCommand struct {
@@ -71,11 +92,14 @@ type TerminationHookCommand struct {
 	Args    []string
 }
 
-func (cmd *TerminationHookCommand) Run() error {
+func (cmd *TerminationHookCommand) Run(info OpInfo) error {
 	cmdShow := strings.Join(append([]string{cmd.Command}, cmd.Args...), " ")
 	fmt.Printf("Running termination hook command: %s\n", cmdShow)
 
-	out, err := exec.Command(cmd.Command, cmd.Args...).Output()
+	hookCmd := exec.Command(cmd.Command, cmd.Args...)
+	hookCmd.Env = append(os.Environ(), info.GetEnv()...)
+
+	out, err := hookCmd.Output()
 	if err != nil {
 		return err
 	}
@@ -89,13 +113,19 @@ type TerminationHookHttpCall struct {
 	Endpoint string
 }
 
-func (call *TerminationHookHttpCall) Run() error {
+func (call *TerminationHookHttpCall) Run(info OpInfo) error {
 	fmt.Printf("Running termination hook http call: %s %s\n", call.Method, call.Endpoint)
 
-	req, reqErr := http.NewRequest(call.Method, call.Endpoint, nil)
+	body, marErr := json.Marshal(info)
+	if marErr != nil {
+		return marErr
+	}
+
+	req, reqErr := http.NewRequest(call.Method, call.Endpoint, bytes.NewBuffer(body))
 	if reqErr != nil {
 		return reqErr
 	}
+	req.Header.Set("Content-Type", "application/json")
 
 	_, resErr := http.DefaultClient.Do(req)
 	if resErr != nil {
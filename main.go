@@ -1,67 +1,268 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"os"
+	"strings"
 	"time"
+	_ "time/tzdata"
 
 	"github.com/Ferlab-Ste-Justine/terracd/cmd"
 	"github.com/Ferlab-Ste-Justine/terracd/config"
+	"github.com/Ferlab-Ste-Justine/terracd/daemon"
 	"github.com/Ferlab-Ste-Justine/terracd/fs"
 	"github.com/Ferlab-Ste-Justine/terracd/hook"
 	"github.com/Ferlab-Ste-Justine/terracd/metrics"
+	"github.com/Ferlab-Ste-Justine/terracd/recurrence"
 	"github.com/Ferlab-Ste-Justine/terracd/state"
 )
 
-func MainNoExit() int {
-	conf, configErr := config.GetConfig()
-	if configErr != nil {
-		fmt.Println(configErr.Error())
+func terminate(conf config.Config, opResult hook.OpResult, opInfo hook.OpInfo, cmdInfo metrics.CommandInfo, providers []metrics.Provider) int {
+	now := time.Now()
+	hookErr := conf.TerminationHooks.Run(opResult, opInfo)
+	metricsErr := metrics.PushMetrics(conf.Metrics, cmdInfo, providers, now)
+
+	if hookErr != nil {
+		fmt.Println(hookErr.Error())
+	}
+
+	if metricsErr != nil {
+		fmt.Println(metricsErr.Error())
+	}
+
+	if hookErr != nil || metricsErr != nil {
 		return 1
 	}
 
+	return 0
+}
+
+func runIteration(ctx context.Context, conf config.Config, stackName string) (hook.OpResult, int) {
 	paths := fs.GetPaths(conf.WorkingDirectory, conf.DataPath)
 
-	var skipped bool
-	usedProviders := []metrics.Provider{}
-	execErr := state.WrapInState(func(st state.State) (state.State, error) {
-		var newSt state.State
-		var err error
-		newSt, skipped, usedProviders, err = cmd.RunConfig(paths, conf, st)
+	var info cmd.RunInfo
+	execErr := state.WrapInState(ctx, func(ctx context.Context, st state.State, store state.StateStore) (state.State, error) {
+		startedAt := time.Now()
+		newSt, runInfo, err := cmd.RunConfig(ctx, paths, conf, st, store)
+		info = runInfo
+		run := getRun(conf, info, startedAt, err)
+		if err != nil {
+			newSt.Failures = st.Failures.Record(conf.Command, recurrence.Occurrence{
+				CommitHashes: info.CommitHashes,
+				Fingerprint: info.Fingerprint,
+				Targets: conf.Targets,
+				Replace: conf.Replace,
+			}, run.Error)
+		} else if !info.Skipped {
+			newSt.Failures = recurrence.Failures{}
+		}
+
+		if err != nil || !info.Skipped || info.SkipReason == "awaiting_approval" {
+			newSt.AddRun(run, conf.History.GetSize())
+		}
+
 		return newSt, err
 	}, conf.StateStore, paths)
-	
+
 
 	var opResult hook.OpResult
 	if execErr != nil {
 		fmt.Println(execErr.Error())
 		opResult = hook.OpFailure
-	} else if skipped {
+	} else if info.Skipped {
 		opResult = hook.OpSkip
 	} else {
 		opResult = hook.OpSuccess
 	}
 
-	now := time.Now()
-	hookErr := conf.TerminationHooks.Run(opResult)
-	metricsErr := metrics.PushMetrics(conf.Metrics, conf.Command, opResult.ToString(), usedProviders, now)
+	opInfo := hook.OpInfo{
+		"command": conf.Command,
+		"result": opResult.ToString(),
+	}
+	if stackName != "" {
+		opInfo["stack"] = stackName
+	}
+	if info.SkipReason != "" {
+		opInfo["skip_reason"] = info.SkipReason
+	}
+	if info.Drift != cmd.DriftUndefined {
+		opInfo["drift"] = info.Drift.ToString()
+	}
 
-	if hookErr != nil {
-		fmt.Println(hookErr.Error())
+	var planChanges map[string]int64
+	if info.PlanSummary != nil {
+		planChanges = info.PlanSummary.Totals.ToMap()
+		opInfo["plan_summary"] = paths.PlanSummary
+		for action, count := range planChanges {
+			opInfo[fmt.Sprintf("plan_%s", action)] = fmt.Sprintf("%d", count)
+		}
 	}
 
-	if metricsErr != nil {
-		fmt.Println(metricsErr.Error())
+	if info.PendingPlanHash != "" {
+		opInfo["pending_plan_hash"] = info.PendingPlanHash
+	}
+
+	if len(info.ForbiddenOperations) > 0 {
+		addresses := []string{}
+		for _, violation := range info.ForbiddenOperations {
+			addresses = append(addresses, violation.Address)
+		}
+		opInfo["forbidden_operations"] = fmt.Sprintf("%d", len(info.ForbiddenOperations))
+		opInfo["forbidden_operations_addresses"] = strings.Join(addresses, ",")
+	}
+
+	code := terminate(conf, opResult, opInfo, metrics.CommandInfo{
+		Command: conf.Command,
+		Result: opResult.ToString(),
+		Drift: info.Drift.ToString(),
+		PlanChanges: planChanges,
+		ForbiddenOperations: int64(len(info.ForbiddenOperations)),
+	}, info.Providers)
+
+	if execErr != nil {
+		return opResult, 1
+	}
+
+	return opResult, code
+}
+
+func runConfig(ctx context.Context, conf config.Config) int {
+	if conf.HasStacks() {
+		return runStacks(ctx, conf)
 	}
 
-	if hookErr != nil || metricsErr != nil || execErr != nil {
+	_, code := runIteration(ctx, conf, "")
+	return code
+}
+
+func applyTrigger(conf config.Config, trigger daemon.Trigger) config.Config {
+	if trigger.Force {
+		fmt.Println("Info: Iteration was forced. Ignoring the recurrence policy.")
+	}
+
+	adjust := func(c config.Config) config.Config {
+		if trigger.Command != "" {
+			c.Command = trigger.Command
+		}
+		if trigger.Force {
+			c.Recurrence = recurrence.Recurrence{}
+		}
+		return c
+	}
+
+	adjusted := adjust(conf)
+	if conf.HasStacks() {
+		adjusted.Stacks = config.Stacks{}
+		for _, stack := range conf.Stacks {
+			stack.Config = adjust(stack.Config)
+			adjusted.Stacks = append(adjusted.Stacks, stack)
+		}
+	}
+
+	return adjusted
+}
+
+func readState(conf config.Config) (interface{}, error) {
+	if !conf.HasStacks() {
+		return state.ReadState(conf.StateStore, fs.GetPaths(conf.WorkingDirectory, conf.DataPath))
+	}
+
+	states := map[string]state.State{}
+	for _, stack := range conf.Stacks {
+		st, stErr := state.ReadState(stack.Config.StateStore, fs.GetPaths(stack.Config.WorkingDirectory, stack.Config.DataPath))
+		if stErr != nil {
+			return states, errors.New(fmt.Sprintf("Error reading the state of stack \"%s\": %s", stack.Name, stErr.Error()))
+		}
+		states[stack.Name] = st
+	}
+
+	return states, nil
+}
+
+func unlock(conf config.Config) int {
+	if !conf.HasStacks() {
+		err := state.ForceUnlock(conf.StateStore, fs.GetPaths(conf.WorkingDirectory, conf.DataPath))
+		if err != nil {
+			fmt.Println(err.Error())
+			return 1
+		}
+
+		return 0
+	}
+
+	code := 0
+	for _, stack := range conf.Stacks {
+		fmt.Printf("Info: Releasing the lock on the state of stack %s.\n", stack.Name)
+		err := state.ForceUnlock(stack.Config.StateStore, fs.GetPaths(stack.Config.WorkingDirectory, stack.Config.DataPath))
+		if err != nil {
+			fmt.Println(err.Error())
+			code = 1
+		}
+	}
+
+	return code
+}
+
+func validateConfig(overrides config.Overrides) int {
+	err := config.ValidateConfig(overrides)
+	if err != nil {
+		fmt.Println(err.Error())
 		return 1
 	}
 
+	fmt.Println("Info: The configuration is valid.")
 	return 0
 }
 
+func MainNoExit(arguments []string) int {
+	args, argsErr := parseArgs(arguments)
+	if argsErr != nil {
+		if errors.Is(argsErr, flag.ErrHelp) {
+			return 0
+		}
+
+		fmt.Println(argsErr.Error())
+		return 1
+	}
+
+	if args.Subcommand == "validate-config" {
+		return validateConfig(args.Overrides)
+	}
+
+	conf, configErr := config.GetConfig(args.Overrides)
+	if configErr != nil {
+		fmt.Println(configErr.Error())
+		return 1
+	}
+
+	if args.Subcommand == "unlock" {
+		return unlock(conf)
+	}
+
+	if args.Subcommand == "history" {
+		return history(conf)
+	}
+
+	//The other subcommands execute a one-off command, even if the configuration defines a daemon
+	if args.Subcommand == "run" && conf.Daemon.IsDefined() {
+		return daemon.Run(conf.Daemon, conf.Command, conf.GetAllSources(), conf.GetAllTriggers(), daemon.Callbacks{
+			Iteration: func(ctx context.Context, trigger daemon.Trigger) int {
+				return runConfig(ctx, applyTrigger(conf, trigger))
+			},
+			ReadState: func() (interface{}, error) {
+				return readState(conf)
+			},
+			ValidateCommand: config.ValidateCommand,
+		})
+	}
+
+	return runConfig(context.Background(), conf)
+}
+
 func main() {
-	code := MainNoExit()
+	code := MainNoExit(os.Args[1:])
 	os.Exit(code)
 }
@@ -4,7 +4,15 @@ import (
 	"time"
 )
 
-func PushMetrics(conf MetricsClientConfig, cmd string, result string, providers []Provider, now time.Time) error {
+type CommandInfo struct {
+	Command             string
+	Result              string
+	Drift               string
+	PlanChanges         map[string]int64
+	ForbiddenOperations int64
+}
+
+func PushMetrics(conf MetricsClientConfig, info CommandInfo, providers []Provider, now time.Time) error {
 	if !conf.IsDefined() {
 		return nil
 	}
@@ -19,5 +27,5 @@ func PushMetrics(conf MetricsClientConfig, cmd string, result string, providers
 		return err
 	}
 
-	return cli.Push(cmd, result, providers, now)
+	return cli.Push(info, providers, now)
 }
\ No newline at end of file
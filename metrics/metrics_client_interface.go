@@ -6,5 +6,5 @@ import (
 
 type MetricsClient interface {
     Initialize() error
-	Push(cmd string, result string, providers []Provider, now time.Time) error
+	Push(info CommandInfo, providers []Provider, now time.Time) error
 }
\ No newline at end of file
@@ -39,17 +39,47 @@ func (cli *PromPushGateway) Initialize() error {
 	return nil
 }
 
-func (cli *PromPushGateway) Push(cmd string, result string, providers []Provider, now time.Time) error {
+func (cli *PromPushGateway) Push(info CommandInfo, providers []Provider, now time.Time) error {
 	currentTime := now.Unix()
 
 	cmdTimestamp := prometheus.NewGauge(prometheus.GaugeOpts{
 		Name: "terracd_command_timestamp_seconds",
 		Help: "Timestamp of completion for terracd command in seconds since epoch.",
-		ConstLabels: prometheus.Labels{"command": cmd, "result": result},
+		ConstLabels: prometheus.Labels{"command": info.Command, "result": info.Result},
 	})
 	cmdTimestamp.Set(float64(currentTime))
 	cli.pusher = cli.pusher.Collector(cmdTimestamp)
 
+	if info.Drift != "" {
+		driftTimestamp := prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "terracd_drift_timestamp_seconds",
+			Help: "Timestamp of the last terracd drift detection in seconds since epoch, labeled with its classification.",
+			ConstLabels: prometheus.Labels{"status": info.Drift},
+		})
+		driftTimestamp.Set(float64(currentTime))
+		cli.pusher = cli.pusher.Collector(driftTimestamp)
+	}
+
+	for action, count := range info.PlanChanges {
+		planChanges := prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "terracd_plan_resource_changes",
+			Help: "Number of resources changed by the last terracd plan, by action.",
+			ConstLabels: prometheus.Labels{"command": info.Command, "action": action},
+		})
+		planChanges.Set(float64(count))
+		cli.pusher = cli.pusher.Collector(planChanges)
+	}
+
+	if info.ForbiddenOperations > 0 {
+		forbiddenOps := prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "terracd_forbidden_operation_violations",
+			Help: "Number of forbidden operations the last terracd plan attempted on protected resources.",
+			ConstLabels: prometheus.Labels{"command": info.Command},
+		})
+		forbiddenOps.Set(float64(info.ForbiddenOperations))
+		cli.pusher = cli.pusher.Collector(forbiddenOps)
+	}
+
 	for _, provider := range providers {
 		providerUseTimestamp := prometheus.NewGauge(prometheus.GaugeOpts{
 			Name: "terracd_provider_use_timestamp_seconds",
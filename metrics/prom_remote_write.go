@@ -38,7 +38,7 @@ func (cli *PromRemoteWrite) Initialize() error {
 	return nil
 }
 
-func (cli *PromRemoteWrite) Push(cmd string, result string, providers []Provider, now time.Time) error {
+func (cli *PromRemoteWrite) Push(info CommandInfo, providers []Provider, now time.Time) error {
 	currentTime := now.Unix()
 	
 	promTS := []prompb.TimeSeries{}
@@ -47,12 +47,46 @@ func (cli *PromRemoteWrite) Push(cmd string, result string, providers []Provider
 		Labels: []prompb.Label{
 			prompb.Label{Name: "__name__", Value: "terracd_command_timestamp_seconds"},
 			prompb.Label{Name: "job", Value: cli.BaseConfig.JobName},
-			prompb.Label{Name: "command", Value: cmd},
-			prompb.Label{Name: "result", Value: result},
+			prompb.Label{Name: "command", Value: info.Command},
+			prompb.Label{Name: "result", Value: info.Result},
 		}, 
 		Samples: []prompb.Sample{prompb.Sample{Timestamp: now.UnixMilli(), Value: float64(currentTime)}},
 	})
 
+	if info.Drift != "" {
+		promTS = append(promTS, prompb.TimeSeries{
+			Labels: []prompb.Label{
+				prompb.Label{Name: "__name__", Value: "terracd_drift_timestamp_seconds"},
+				prompb.Label{Name: "job", Value: cli.BaseConfig.JobName},
+				prompb.Label{Name: "status", Value: info.Drift},
+			}, 
+			Samples: []prompb.Sample{prompb.Sample{Timestamp: now.UnixMilli(), Value: float64(currentTime)}},
+		})
+	}
+
+	for action, count := range info.PlanChanges {
+		promTS = append(promTS, prompb.TimeSeries{
+			Labels: []prompb.Label{
+				prompb.Label{Name: "__name__", Value: "terracd_plan_resource_changes"},
+				prompb.Label{Name: "job", Value: cli.BaseConfig.JobName},
+				prompb.Label{Name: "command", Value: info.Command},
+				prompb.Label{Name: "action", Value: action},
+			}, 
+			Samples: []prompb.Sample{prompb.Sample{Timestamp: now.UnixMilli(), Value: float64(count)}},
+		})
+	}
+
+	if info.ForbiddenOperations > 0 {
+		promTS = append(promTS, prompb.TimeSeries{
+			Labels: []prompb.Label{
+				prompb.Label{Name: "__name__", Value: "terracd_forbidden_operation_violations"},
+				prompb.Label{Name: "job", Value: cli.BaseConfig.JobName},
+				prompb.Label{Name: "command", Value: info.Command},
+			}, 
+			Samples: []prompb.Sample{prompb.Sample{Timestamp: now.UnixMilli(), Value: float64(info.ForbiddenOperations)}},
+		})
+	}
+
 	for _, provider := range providers {
 		promTS = append(promTS, prompb.TimeSeries{
 			Labels: []prompb.Label{
@@ -0,0 +1,215 @@
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type cronField struct {
+	min   int
+	max   int
+	names map[string]int
+}
+
+var (
+	cronMinutes = cronField{min: 0, max: 59}
+	cronHours = cronField{min: 0, max: 23}
+	cronDaysOfMonth = cronField{min: 1, max: 31}
+	cronMonths = cronField{min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	cronDaysOfWeek = cronField{min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+func (field *cronField) parseValue(value string) (int, error) {
+	if num, ok := field.names[strings.ToLower(value)]; ok {
+		return num, nil
+	}
+
+	num, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("invalid value \"%s\"", value))
+	}
+
+	if num < field.min || num > field.max {
+		return 0, errors.New(fmt.Sprintf("value %d is outside the range %d-%d", num, field.min, field.max))
+	}
+
+	return num, nil
+}
+
+//Returns the values of the field and whether it is a bare wildcard. Like in vixie cron, a wildcard with a step (ex: */2) is a restriction.
+func (field *cronField) parse(expr string) (map[int]bool, bool, error) {
+	values := map[int]bool{}
+	wildcard := false
+
+	for _, part := range strings.Split(expr, ",") {
+		rangeExpr := part
+		step := 1
+
+		if strings.Contains(part, "/") {
+			stepParts := strings.SplitN(part, "/", 2)
+			rangeExpr = stepParts[0]
+
+			var stepErr error
+			step, stepErr = strconv.Atoi(stepParts[1])
+			if stepErr != nil || step <= 0 {
+				return values, false, errors.New(fmt.Sprintf("invalid step \"%s\"", stepParts[1]))
+			}
+		}
+
+		start := field.min
+		end := field.max
+		if rangeExpr == "*" {
+			if part == "*" {
+				wildcard = true
+			}
+		} else if strings.Contains(rangeExpr, "-") {
+			rangeParts := strings.SplitN(rangeExpr, "-", 2)
+
+			var startErr, endErr error
+			start, startErr = field.parseValue(rangeParts[0])
+			if startErr != nil {
+				return values, false, startErr
+			}
+
+			end, endErr = field.parseValue(rangeParts[1])
+			if endErr != nil {
+				return values, false, endErr
+			}
+
+			if start > end {
+				return values, false, errors.New(fmt.Sprintf("invalid range \"%s\"", rangeExpr))
+			}
+		} else {
+			var valueErr error
+			start, valueErr = field.parseValue(rangeExpr)
+			if valueErr != nil {
+				return values, false, valueErr
+			}
+
+			if step == 1 {
+				end = start
+			}
+		}
+
+		for value := start; value <= end; value += step {
+			values[value] = true
+		}
+	}
+
+	return values, wildcard, nil
+}
+
+type CronSchedule struct {
+	minutes        map[int]bool
+	hours          map[int]bool
+	daysOfMonth    map[int]bool
+	months         map[int]bool
+	daysOfWeek     map[int]bool
+	anyDayOfMonth  bool
+	anyDayOfWeek   bool
+}
+
+func ParseCronSchedule(expr string) (CronSchedule, error) {
+	var sched CronSchedule
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return sched, errors.New(fmt.Sprintf("Cron expression \"%s\" should have 5 fields (minute, hour, day of month, month and day of week)", expr))
+	}
+
+	var err error
+	sched.minutes, _, err = cronMinutes.parse(fields[0])
+	if err == nil {
+		sched.hours, _, err = cronHours.parse(fields[1])
+	}
+	if err == nil {
+		sched.daysOfMonth, sched.anyDayOfMonth, err = cronDaysOfMonth.parse(fields[2])
+	}
+	if err == nil {
+		sched.months, _, err = cronMonths.parse(fields[3])
+	}
+	if err == nil {
+		sched.daysOfWeek, sched.anyDayOfWeek, err = cronDaysOfWeek.parse(fields[4])
+	}
+	if err != nil {
+		return sched, errors.New(fmt.Sprintf("Error parsing cron expression \"%s\": %s", expr, err.Error()))
+	}
+
+	if sched.daysOfWeek[7] {
+		sched.daysOfWeek[0] = true
+	}
+
+	return sched, nil
+}
+
+func (sched *CronSchedule) matchesDay(t time.Time) bool {
+	domMatch := sched.daysOfMonth[t.Day()]
+	dowMatch := sched.daysOfWeek[int(t.Weekday())]
+
+	if sched.anyDayOfMonth || sched.anyDayOfWeek {
+		return domMatch && dowMatch
+	}
+
+	return domMatch || dowMatch
+}
+
+//Returns the wall clock time of the given time, as a time in utc
+func toWallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+//Returns the time at which the wall clock of the location shows the given wall clock time.
+//A wall clock time skipped when clocks are set forward is moved forward by the length of the skipped period
+//and a wall clock time repeated when clocks are set back resolves to its first occurrence.
+func fromWallClock(wall time.Time, loc *time.Location) time.Time {
+	t := time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), wall.Nanosecond(), loc)
+	return t.Add(wall.Sub(toWallClock(t)))
+}
+
+//Returns the first time matching the schedule strictly after the given time, or the zero time if none is found within 5 years.
+//The schedule is evaluated on the wall clock of the location of the given time, so that a daylight saving time change
+//neither skips an execution nor executes it twice.
+func (sched *CronSchedule) Next(after time.Time) time.Time {
+	t := toWallClock(after).Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if !sched.months[int(t.Month())] {
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location()).AddDate(0, 1, 0)
+			continue
+		}
+
+		if !sched.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()).AddDate(0, 0, 1)
+			continue
+		}
+
+		if !sched.hours[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location()).Add(time.Hour)
+			continue
+		}
+
+		if !sched.minutes[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		next := fromWallClock(t, after.Location())
+		if !next.After(after) {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return next
+	}
+
+	return time.Time{}
+}
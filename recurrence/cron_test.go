@@ -0,0 +1,128 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestParseCronScheduleErrors(t *testing.T) {
+	exprs := []string{
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"5-1 * * * *",
+		"* * * foo *",
+		"* * * * sunday",
+	}
+
+	for _, expr := range exprs {
+		_, err := ParseCronSchedule(expr)
+		if err == nil {
+			t.Errorf("Expected cron expression \"%s\" to be invalid and it wasn't", expr)
+		}
+	}
+}
+
+func TestCronScheduleNext(t *testing.T) {
+	tests := []struct {
+		expr     string
+		after    time.Time
+		expected time.Time
+	}{
+		//Minutes and hours with ranges, steps and lists
+		{"*/15 * * * *", time.Date(2026, 1, 5, 10, 7, 0, 0, time.UTC), time.Date(2026, 1, 5, 10, 15, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, 1, 5, 10, 15, 0, 0, time.UTC), time.Date(2026, 1, 5, 10, 30, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, 1, 5, 10, 14, 59, 0, time.UTC), time.Date(2026, 1, 5, 10, 15, 0, 0, time.UTC)},
+		{"5,35 * * * *", time.Date(2026, 1, 5, 10, 40, 0, 0, time.UTC), time.Date(2026, 1, 5, 11, 5, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2026, 1, 5, 10, 0, 0, 0, time.UTC), time.Date(2026, 1, 5, 13, 0, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2026, 1, 5, 17, 0, 0, 0, time.UTC), time.Date(2026, 1, 6, 9, 0, 0, 0, time.UTC)},
+		{"10-12 3 * * *", time.Date(2026, 1, 5, 3, 11, 0, 0, time.UTC), time.Date(2026, 1, 5, 3, 12, 0, 0, time.UTC)},
+		{"30 20/2 * * *", time.Date(2026, 1, 5, 21, 0, 0, 0, time.UTC), time.Date(2026, 1, 5, 22, 30, 0, 0, time.UTC)},
+		//Days of month and months
+		{"0 0 1,15 * *", time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC), time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 * *", time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 jan,jul *", time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Time{}},
+		//Days of week, with 7 and names for sunday
+		{"30 6 * * mon-fri", time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC), time.Date(2026, 1, 12, 6, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 1, 4, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * SUN", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 1, 4, 0, 0, 0, 0, time.UTC)},
+		//Either the day of month or the day of week must match when both are restricted
+		{"0 0 13 * fri", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"0 0 13 * fri", time.Date(2026, 1, 9, 0, 0, 0, 0, time.UTC), time.Date(2026, 1, 13, 0, 0, 0, 0, time.UTC)},
+		//Both must match when either is a bare wildcard
+		{"0 0 13 * *", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 1, 13, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * fri", time.Date(2026, 1, 3, 0, 0, 0, 0, time.UTC), time.Date(2026, 1, 9, 0, 0, 0, 0, time.UTC)},
+		//A wildcard with a step is a restriction
+		{"0 0 */10 * mon", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{"0 0 */10 * mon", time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC), time.Date(2026, 1, 11, 0, 0, 0, 0, time.UTC)},
+		{"0 0 */1 * mon", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * */2", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 1, 3, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		sched, err := ParseCronSchedule(test.expr)
+		if err != nil {
+			t.Errorf("Unexpected error parsing cron expression \"%s\": %s", test.expr, err.Error())
+			continue
+		}
+
+		next := sched.Next(test.after)
+		if !next.Equal(test.expected) {
+			t.Errorf("Expected next time of \"%s\" after %s to be %s and it was %s", test.expr, test.after, test.expected, next)
+		}
+	}
+}
+
+func TestCronScheduleNextDaylightSavingTime(t *testing.T) {
+	loc, locErr := time.LoadLocation("America/Toronto")
+	if locErr != nil {
+		t.Errorf("%s", locErr.Error())
+		return
+	}
+
+	//In 2026, clocks are set forward from 02:00 to 03:00 on March 8 and back from 02:00 to 01:00 on November 1
+	springForward := time.Date(2026, 3, 8, 0, 0, 0, 0, loc)
+	fallBack := time.Date(2026, 11, 1, 0, 0, 0, 0, loc)
+	firstOneThirty := time.Date(2026, 11, 1, 1, 30, 0, 0, loc)
+	secondOneThirty := firstOneThirty.Add(time.Hour)
+
+	tests := []struct {
+		expr     string
+		after    time.Time
+		expected time.Time
+	}{
+		//Times skipped when clocks are set forward are moved forward by an hour
+		{"30 2 * * *", springForward, time.Date(2026, 3, 8, 3, 30, 0, 0, loc)},
+		{"0 3 * * *", springForward, time.Date(2026, 3, 8, 3, 0, 0, 0, loc)},
+		{"0 * * * *", springForward.Add(time.Hour), time.Date(2026, 3, 8, 3, 0, 0, 0, loc)},
+		{"0 12 * * *", time.Date(2026, 3, 7, 12, 0, 0, 0, loc), time.Date(2026, 3, 8, 12, 0, 0, 0, loc)},
+		//Times repeated when clocks are set back only occur once
+		{"30 1 * * *", fallBack, firstOneThirty},
+		{"30 1 * * *", firstOneThirty, time.Date(2026, 11, 2, 1, 30, 0, 0, loc)},
+		{"30 1 * * *", secondOneThirty, time.Date(2026, 11, 2, 1, 30, 0, 0, loc)},
+		{"0 * * * *", firstOneThirty, time.Date(2026, 11, 1, 2, 0, 0, 0, loc)},
+		{"0 12 * * *", time.Date(2026, 10, 31, 12, 0, 0, 0, loc), time.Date(2026, 11, 1, 12, 0, 0, 0, loc)},
+	}
+
+	for _, test := range tests {
+		sched, err := ParseCronSchedule(test.expr)
+		if err != nil {
+			t.Errorf("Unexpected error parsing cron expression \"%s\": %s", test.expr, err.Error())
+			continue
+		}
+
+		next := sched.Next(test.after)
+		if !next.Equal(test.expected) {
+			t.Errorf("Expected next time of \"%s\" after %s to be %s and it was %s", test.expr, test.after, test.expected, next)
+		}
+	}
+}
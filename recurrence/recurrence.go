@@ -1,29 +1,230 @@
 package recurrence
 
 import (
+	"errors"
+	"fmt"
 	"time"
+
 	"github.com/Ferlab-Ste-Justine/terracd/source"
 )
 
+type RetryBackoff struct {
+	Initial time.Duration
+	Max     time.Duration
+}
+
+func (backoff *RetryBackoff) IsDefined() bool {
+	return backoff.Initial > 0
+}
+
+//Maximum delay, which defaults to 24 hours or to the initial delay if it is longer
+func (backoff *RetryBackoff) GetMax() time.Duration {
+	if backoff.Max == 0 {
+		if backoff.Initial > 24 * time.Hour {
+			return backoff.Initial
+		}
+		return 24 * time.Hour
+	}
+
+	return backoff.Max
+}
+
+//Delay before retrying after the given number of consecutive failures, doubling after each failure up to the maximum delay
+func (backoff *RetryBackoff) GetDelay(failures int64) time.Duration {
+	max := backoff.GetMax()
+	delay := backoff.Initial
+	for idx := int64(1); idx < failures && delay < max; idx++ {
+		delay = delay * 2
+	}
+
+	if delay > max {
+		return max
+	}
+
+	return delay
+}
+
+//Reasons for which the recurrence policy skips an execution
+const (
+	SkipBlackout      = "blackout"
+	SkipOutsideWindow = "outside_window"
+	SkipMaxFailures   = "max_failures"
+	SkipRetryBackoff  = "retry_backoff"
+	SkipNotDue        = "not_due"
+)
+
 type Recurrence struct {
 	MinInterval  time.Duration `yaml:"min_interval"`
 	GitTriggers  bool          `yaml:"git_triggers"`
+	RetryBackoff RetryBackoff  `yaml:"retry_backoff"`
+	MaxFailures  int64         `yaml:"max_failures"`
+	Schedule     string
+	Timezone     string
+	Windows      []Window
+	Blackouts    []Blackout
 }
 
 func (rec *Recurrence) IsDefined() bool {
-	return rec.MinInterval > 0 
+	return rec.MinInterval > 0 || rec.RetryBackoff.IsDefined() || rec.MaxFailures > 0 || rec.Schedule != "" || len(rec.Windows) > 0 || len(rec.Blackouts) > 0
+}
+
+func (rec *Recurrence) getLocation() *time.Location {
+	if rec.Timezone == "" {
+		return time.Local
+	}
+
+	loc, err := time.LoadLocation(rec.Timezone)
+	if err != nil {
+		return time.Local
+	}
+
+	return loc
+}
+
+//Validates the schedule and timezone. Windows and blackouts are validated separately.
+func (rec *Recurrence) Validate() error {
+	if rec.Schedule != "" {
+		_, schedErr := ParseCronSchedule(rec.Schedule)
+		if schedErr != nil {
+			return schedErr
+		}
+	}
+
+	if rec.Timezone != "" {
+		_, locErr := time.LoadLocation(rec.Timezone)
+		if locErr != nil {
+			return errors.New(fmt.Sprintf("Error loading recurrence timezone \"%s\": %s", rec.Timezone, locErr.Error()))
+		}
+	}
+
+	return nil
+}
+
+//Returns whether the command is allowed to run at the given time according to the windows and blackouts, and if not, the reason
+func (rec *Recurrence) IsAllowed(cmd string, t time.Time) (bool, string) {
+	loc := rec.getLocation()
+	t = t.In(loc)
+
+	for _, blackout := range rec.Blackouts {
+		if appliesToCommand(blackout.Commands, cmd) && blackout.Contains(t, loc) {
+			return false, SkipBlackout
+		}
+	}
+
+	restricted := false
+	for _, window := range rec.Windows {
+		if !appliesToCommand(window.Commands, cmd) {
+			continue
+		}
+
+		restricted = true
+		if window.Contains(t) {
+			return true, ""
+		}
+	}
+
+	if restricted {
+		return false, SkipOutsideWindow
+	}
+
+	return true, ""
+}
+
+//Returns whether a time scheduled by the cron expression elapsed between the two times
+func (rec *Recurrence) scheduleElapsed(last time.Time, next time.Time) bool {
+	sched, err := ParseCronSchedule(rec.Schedule)
+	if err != nil {
+		return false
+	}
+
+	scheduled := sched.Next(last.In(rec.getLocation()))
+	return !scheduled.IsZero() && !scheduled.After(next)
 }
 
 type Occurrence struct {
 	CommitHashes []source.CommitHash `yaml:"commit_hashes"`
+	Fingerprint  string
+	Trigger      string
+	Targets      []string
+	Replace      []string
 	Timestamp    time.Time
 }
 
+func listsDiffer(first []string, second []string) bool {
+	if len(first) != len(second) {
+		return true
+	}
+
+	for idx, elem := range first {
+		if elem != second[idx] {
+			return true
+		}
+	}
+
+	return false
+}
+
+//Returns whether the resources targeted or replaced differ between the occurrences
+func (occ *Occurrence) OptionsChanged(other *Occurrence) bool {
+	return listsDiffer(occ.Targets, other.Targets) || listsDiffer(occ.Replace, other.Replace)
+}
+
+//Returns whether the commit hashes of the git sources or the content of the sources differ between the occurrences
+func (occ *Occurrence) SourcesChanged(other *Occurrence) bool {
+	return GitReposChanged(occ.CommitHashes, other.CommitHashes) || FingerprintChanged(occ.Fingerprint, other.Fingerprint)
+}
+
 type CommandOccurrence struct {
 	Command    string
 	Occurrence Occurrence
 }
 
+//Consecutive failures of a command with the same sources
+type Failures struct {
+	Command      string
+	Count        int64
+	LastError    string              `yaml:"last_error"`
+	Timestamp    time.Time
+	CommitHashes []source.CommitHash `yaml:"commit_hashes"`
+	Fingerprint  string
+	Targets      []string
+	Replace      []string
+}
+
+func (failures *Failures) getOccurrence() *Occurrence {
+	return &Occurrence{
+		CommitHashes: failures.CommitHashes,
+		Fingerprint: failures.Fingerprint,
+		Targets: failures.Targets,
+		Replace: failures.Replace,
+	}
+}
+
+//Returns the failures with the given failure added. The count restarts if the command, sources or targeted resources differ from the previous failures.
+func (failures Failures) Record(cmd string, occ Occurrence, message string) Failures {
+	last := failures.getOccurrence()
+	count := int64(1)
+	if failures.Count > 0 && failures.Command == cmd && !last.SourcesChanged(&occ) && !last.OptionsChanged(&occ) {
+		count = failures.Count + 1
+	}
+
+	return Failures{
+		Command: cmd,
+		Count: count,
+		LastError: message,
+		Timestamp: time.Now(),
+		CommitHashes: occ.CommitHashes,
+		Fingerprint: occ.Fingerprint,
+		Targets: occ.Targets,
+		Replace: occ.Replace,
+	}
+}
+
+//Returns whether the fingerprints of the content of the sources differ. A missing fingerprint, as in a state recorded before they were computed, does not count as a change.
+func FingerprintChanged(first string, second string) bool {
+	return first != "" && second != "" && first != second
+}
+
 func GitReposChanged(first []source.CommitHash, second []source.CommitHash) bool {
 	if len(first) != len(second) {
 		return true
@@ -46,32 +247,88 @@ func GitReposChanged(first []source.CommitHash, second []source.CommitHash) bool
 	return false
 }
 
-func GenerateCommandOccurrence(cmd string, hashes []source.CommitHash) *CommandOccurrence {
+//Returns an occurrence of the command with the current time
+func GenerateCommandOccurrence(cmd string, occ Occurrence) *CommandOccurrence {
+	occ.Timestamp = time.Now()
 	return &CommandOccurrence{
 		Command: cmd,
-		Occurrence: Occurrence{
-			CommitHashes: hashes,
-			Timestamp: time.Now(),
-		},
+		Occurrence: occ,
 	}
 }
 
-func (last *CommandOccurrence) ShouldOccur(rec *Recurrence, next *CommandOccurrence) bool {
+//Returns whether the failures of previous attempts with the same sources prevent a retry at this time, and if so, the reason
+func (failures *Failures) BlocksRetry(rec *Recurrence, next *CommandOccurrence) (bool, string) {
+	if failures.Count == 0 || failures.Command != next.Command {
+		return false, ""
+	}
+
+	last := failures.getOccurrence()
+	if last.SourcesChanged(&next.Occurrence) || last.OptionsChanged(&next.Occurrence) {
+		return false, ""
+	}
+
+	if rec.MaxFailures > 0 && failures.Count >= rec.MaxFailures {
+		return true, SkipMaxFailures
+	}
+
+	if rec.RetryBackoff.IsDefined() && next.Occurrence.Timestamp.Before(failures.Timestamp.Add(rec.RetryBackoff.GetDelay(failures.Count))) {
+		return true, SkipRetryBackoff
+	}
+
+	return false, ""
+}
+
+func (last *CommandOccurrence) isDue(rec *Recurrence, next *CommandOccurrence) bool {
 	if last.Command != next.Command {
 		return true
 	}
 
+	if next.Occurrence.Trigger != "" && next.Occurrence.Trigger != last.Occurrence.Trigger {
+		return true
+	}
+
+	if last.Occurrence.OptionsChanged(&next.Occurrence) {
+		return true
+	}
+
 	if last.Command == "migrate_backend" {
-		return GitReposChanged(last.Occurrence.CommitHashes, next.Occurrence.CommitHashes)
-	} else if last.Command == "plan" || last.Command == "apply" {
-		if rec.GitTriggers && GitReposChanged(last.Occurrence.CommitHashes, next.Occurrence.CommitHashes) {
+		return last.Occurrence.SourcesChanged(&next.Occurrence)
+	} else if last.Command == "plan" || last.Command == "apply" || last.Command == "drift" {
+		if rec.GitTriggers && last.Occurrence.SourcesChanged(&next.Occurrence) {
 			return true
 		}
 
+		if rec.Schedule != "" && rec.scheduleElapsed(last.Occurrence.Timestamp, next.Occurrence.Timestamp) {
+			return true
+		}
+
+		if rec.Schedule != "" && rec.MinInterval == 0 {
+			return false
+		}
+
 		return last.Occurrence.Timestamp.Add(rec.MinInterval).Before(next.Occurrence.Timestamp)
 	} else if last.Command == "destroy" {
-		return false
+		return last.Occurrence.SourcesChanged(&next.Occurrence)
 	}
 
 	return true
+}
+
+//Returns whether the next command should occur and if not, the reason it should be skipped
+func (last *CommandOccurrence) ShouldOccur(rec *Recurrence, next *CommandOccurrence, failures *Failures) (bool, string) {
+	allowed, reason := rec.IsAllowed(next.Command, next.Occurrence.Timestamp)
+	if !allowed {
+		return false, reason
+	}
+
+	blocked, reason := failures.BlocksRetry(rec, next)
+	if blocked {
+		return false, reason
+	}
+
+	if !last.isDue(rec, next) {
+		return false, SkipNotDue
+	}
+
+	return true, ""
 }
\ No newline at end of file
@@ -0,0 +1,120 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Ferlab-Ste-Justine/terracd/source"
+)
+
+func TestRetryBackoffGetDelay(t *testing.T) {
+	tests := []struct {
+		backoff  RetryBackoff
+		failures int64
+		expected time.Duration
+	}{
+		{RetryBackoff{Initial: time.Minute}, 0, time.Minute},
+		{RetryBackoff{Initial: time.Minute}, 1, time.Minute},
+		{RetryBackoff{Initial: time.Minute}, 2, 2 * time.Minute},
+		{RetryBackoff{Initial: time.Minute}, 4, 8 * time.Minute},
+		{RetryBackoff{Initial: time.Minute, Max: 10 * time.Minute}, 4, 8 * time.Minute},
+		{RetryBackoff{Initial: time.Minute, Max: 10 * time.Minute}, 5, 10 * time.Minute},
+		{RetryBackoff{Initial: time.Minute, Max: 8 * time.Minute}, 4, 8 * time.Minute},
+		{RetryBackoff{Initial: time.Minute, Max: 10 * time.Minute}, 1000000, 10 * time.Minute},
+		//The maximum defaults to 24 hours
+		{RetryBackoff{Initial: time.Hour}, 5, 16 * time.Hour},
+		{RetryBackoff{Initial: time.Hour}, 6, 24 * time.Hour},
+		{RetryBackoff{Initial: time.Hour}, 1000000, 24 * time.Hour},
+		//Or to the initial delay if it is longer
+		{RetryBackoff{Initial: 48 * time.Hour}, 1, 48 * time.Hour},
+		{RetryBackoff{Initial: 48 * time.Hour}, 3, 48 * time.Hour},
+		//An initial delay above the maximum is capped
+		{RetryBackoff{Initial: time.Hour, Max: 30 * time.Minute}, 1, 30 * time.Minute},
+	}
+
+	for _, test := range tests {
+		delay := test.backoff.GetDelay(test.failures)
+		if delay != test.expected {
+			t.Errorf("Expected delay of %v after %d failures to be %s and it was %s", test.backoff, test.failures, test.expected, delay)
+		}
+	}
+}
+
+func TestFailuresRecord(t *testing.T) {
+	hashes := []source.CommitHash{source.CommitHash{Url: "git@github.com:org/repo.git", Ref: "main", Hash: "abc"}}
+	otherHashes := []source.CommitHash{source.CommitHash{Url: "git@github.com:org/repo.git", Ref: "main", Hash: "def"}}
+
+	failures := Failures{}.Record("apply", Occurrence{CommitHashes: hashes, Fingerprint: "fp1"}, "first error")
+	failures = failures.Record("apply", Occurrence{CommitHashes: hashes, Fingerprint: "fp1"}, "second error")
+	if failures.Count != 2 || failures.LastError != "second error" {
+		t.Errorf("Expected 2 consecutive failures with the last error and got %d failures with error \"%s\"", failures.Count, failures.LastError)
+	}
+
+	tests := []struct {
+		name string
+		cmd  string
+		occ  Occurrence
+	}{
+		{"another command", "plan", Occurrence{CommitHashes: hashes, Fingerprint: "fp1"}},
+		{"other commits", "apply", Occurrence{CommitHashes: otherHashes, Fingerprint: "fp1"}},
+		{"another fingerprint", "apply", Occurrence{CommitHashes: hashes, Fingerprint: "fp2"}},
+		{"targets", "apply", Occurrence{CommitHashes: hashes, Fingerprint: "fp1", Targets: []string{"aws_instance.web"}}},
+	}
+
+	for _, test := range tests {
+		recorded := failures.Record(test.cmd, test.occ, "error")
+		if recorded.Count != 1 {
+			t.Errorf("Expected failure count to restart with %s and it was %d", test.name, recorded.Count)
+		}
+	}
+}
+
+func TestFailuresBlocksRetry(t *testing.T) {
+	lastFailure := time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)
+	hashes := []source.CommitHash{source.CommitHash{Url: "git@github.com:org/repo.git", Ref: "main", Hash: "abc"}}
+	failures := Failures{
+		Command: "apply",
+		Count: 3,
+		Timestamp: lastFailure,
+		CommitHashes: hashes,
+		Fingerprint: "fp1",
+	}
+
+	getNext := func(cmd string, delay time.Duration, fingerprint string) *CommandOccurrence {
+		return &CommandOccurrence{
+			Command: cmd,
+			Occurrence: Occurrence{CommitHashes: hashes, Fingerprint: fingerprint, Timestamp: lastFailure.Add(delay)},
+		}
+	}
+
+	backoff := Recurrence{RetryBackoff: RetryBackoff{Initial: time.Minute, Max: 3 * time.Minute}}
+	tests := []struct {
+		name     string
+		rec      Recurrence
+		next     *CommandOccurrence
+		blocked  bool
+		reason   string
+	}{
+		{"no retry policy", Recurrence{}, getNext("apply", 0, "fp1"), false, ""},
+		{"retry before the capped delay", backoff, getNext("apply", 3 * time.Minute - time.Second, "fp1"), true, SkipRetryBackoff},
+		{"retry after the capped delay", backoff, getNext("apply", 3 * time.Minute + time.Second, "fp1"), false, ""},
+		{"retry with other sources", backoff, getNext("apply", time.Second, "fp2"), false, ""},
+		{"retry with another command", backoff, getNext("plan", time.Second, "fp1"), false, ""},
+		{"failures at the maximum", Recurrence{MaxFailures: 3}, getNext("apply", 24 * time.Hour, "fp1"), true, SkipMaxFailures},
+		{"failures under the maximum", Recurrence{MaxFailures: 4}, getNext("apply", 0, "fp1"), false, ""},
+		{"failures at the maximum with other sources", Recurrence{MaxFailures: 3}, getNext("apply", 0, "fp2"), false, ""},
+	}
+
+	for _, test := range tests {
+		blocked, reason := failures.BlocksRetry(&test.rec, test.next)
+		if blocked != test.blocked || reason != test.reason {
+			t.Errorf("Expected retry with %s to be blocked: %t (reason \"%s\") and it was blocked: %t (reason \"%s\")", test.name, test.blocked, test.reason, blocked, reason)
+		}
+	}
+
+	noFailures := Failures{}
+	blocked, _ := noFailures.BlocksRetry(&backoff, getNext("apply", 0, "fp1"))
+	if blocked {
+		t.Errorf("Expected no recorded failures not to block a retry and they did")
+	}
+}
@@ -0,0 +1,170 @@
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+func appliesToCommand(commands []string, cmd string) bool {
+	if len(commands) == 0 {
+		return true
+	}
+
+	for _, command := range commands {
+		if command == cmd {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parseTimeOfDay(value string) (time.Duration, error) {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("Time of day \"%s\" should have the HH:MM format", value))
+	}
+
+	return time.Duration(parsed.Hour()) * time.Hour + time.Duration(parsed.Minute()) * time.Minute, nil
+}
+
+//Time window during which commands are allowed to run
+type Window struct {
+	Days     []string
+	Start    string
+	End      string
+	Commands []string
+}
+
+func (window *Window) Validate() error {
+	for _, day := range window.Days {
+		if _, ok := weekdays[strings.ToLower(day)]; !ok {
+			return errors.New(fmt.Sprintf("Day \"%s\" should be one of sun, mon, tue, wed, thu, fri or sat", day))
+		}
+	}
+
+	if window.Start == "" || window.End == "" {
+		return errors.New("Both the start and end of the window must be defined")
+	}
+
+	_, startErr := parseTimeOfDay(window.Start)
+	if startErr != nil {
+		return startErr
+	}
+
+	_, endErr := parseTimeOfDay(window.End)
+	return endErr
+}
+
+func (window *Window) matchesDay(day time.Weekday) bool {
+	if len(window.Days) == 0 {
+		return true
+	}
+
+	for _, windowDay := range window.Days {
+		if weekdays[strings.ToLower(windowDay)] == day {
+			return true
+		}
+	}
+
+	return false
+}
+
+//Returns whether the time is in the window. A window ending before it starts spans midnight and is attributed to the day it starts.
+func (window *Window) Contains(t time.Time) bool {
+	start, _ := parseTimeOfDay(window.Start)
+	end, _ := parseTimeOfDay(window.End)
+
+	//Measured on the wall clock so that the window is not shifted on days of daylight saving time changes
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	sinceMidnight := time.Duration(t.Hour()) * time.Hour + time.Duration(t.Minute()) * time.Minute + time.Duration(t.Second()) * time.Second
+
+	if start < end {
+		return window.matchesDay(t.Weekday()) && sinceMidnight >= start && sinceMidnight < end
+	}
+
+	if sinceMidnight >= start {
+		return window.matchesDay(t.Weekday())
+	}
+
+	return sinceMidnight < end && window.matchesDay(midnight.AddDate(0, 0, -1).Weekday())
+}
+
+//Period during which commands are not allowed to run
+type Blackout struct {
+	Start       string
+	End         string
+	Description string
+	Commands    []string
+}
+
+//Parses a date or a date and time. The end of a period given as a date includes the whole day.
+func parseBlackoutTime(value string, loc *time.Location, isEnd bool) (time.Time, error) {
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err == nil {
+		return parsed, nil
+	}
+
+	parsed, err = time.ParseInLocation("2006-01-02T15:04", value, loc)
+	if err == nil {
+		return parsed, nil
+	}
+
+	parsed, err = time.ParseInLocation("2006-01-02", value, loc)
+	if err == nil {
+		if isEnd {
+			return parsed.AddDate(0, 0, 1), nil
+		}
+		return parsed, nil
+	}
+
+	return time.Time{}, errors.New(fmt.Sprintf("Time \"%s\" should have the YYYY-MM-DD, YYYY-MM-DDTHH:MM or RFC3339 format", value))
+}
+
+func (blackout *Blackout) getPeriod(loc *time.Location) (time.Time, time.Time, error) {
+	start, startErr := parseBlackoutTime(blackout.Start, loc, false)
+	if startErr != nil {
+		return start, start, startErr
+	}
+
+	end, endErr := parseBlackoutTime(blackout.End, loc, true)
+	if endErr != nil {
+		return start, end, endErr
+	}
+
+	if !end.After(start) {
+		return start, end, errors.New("The end of the blackout must come after its start")
+	}
+
+	return start, end, nil
+}
+
+func (blackout *Blackout) Validate() error {
+	if blackout.Start == "" || blackout.End == "" {
+		return errors.New("Both the start and end of the blackout must be defined")
+	}
+
+	_, _, err := blackout.getPeriod(time.UTC)
+	return err
+}
+
+func (blackout *Blackout) Contains(t time.Time, loc *time.Location) bool {
+	start, end, err := blackout.getPeriod(loc)
+	if err != nil {
+		return false
+	}
+
+	return !t.Before(start) && t.Before(end)
+}
@@ -0,0 +1,95 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestWindowContains(t *testing.T) {
+	//January 5, 2026 is a monday
+	tests := []struct {
+		window   Window
+		t        time.Time
+		expected bool
+	}{
+		{Window{Start: "09:00", End: "17:00"}, time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC), true},
+		{Window{Start: "09:00", End: "17:00"}, time.Date(2026, 1, 5, 16, 59, 0, 0, time.UTC), true},
+		{Window{Start: "09:00", End: "17:00"}, time.Date(2026, 1, 5, 17, 0, 0, 0, time.UTC), false},
+		{Window{Start: "09:00", End: "17:00"}, time.Date(2026, 1, 5, 8, 59, 0, 0, time.UTC), false},
+		{Window{Days: []string{"mon", "tue"}, Start: "09:00", End: "17:00"}, time.Date(2026, 1, 6, 12, 0, 0, 0, time.UTC), true},
+		{Window{Days: []string{"Mon", "Tue"}, Start: "09:00", End: "17:00"}, time.Date(2026, 1, 7, 12, 0, 0, 0, time.UTC), false},
+		//Windows spanning midnight are attributed to the day they start
+		{Window{Days: []string{"fri"}, Start: "22:00", End: "04:00"}, time.Date(2026, 1, 9, 22, 0, 0, 0, time.UTC), true},
+		{Window{Days: []string{"fri"}, Start: "22:00", End: "04:00"}, time.Date(2026, 1, 9, 23, 59, 0, 0, time.UTC), true},
+		{Window{Days: []string{"fri"}, Start: "22:00", End: "04:00"}, time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC), true},
+		{Window{Days: []string{"fri"}, Start: "22:00", End: "04:00"}, time.Date(2026, 1, 10, 3, 59, 0, 0, time.UTC), true},
+		{Window{Days: []string{"fri"}, Start: "22:00", End: "04:00"}, time.Date(2026, 1, 10, 4, 0, 0, 0, time.UTC), false},
+		{Window{Days: []string{"fri"}, Start: "22:00", End: "04:00"}, time.Date(2026, 1, 10, 22, 0, 0, 0, time.UTC), false},
+		{Window{Days: []string{"fri"}, Start: "22:00", End: "04:00"}, time.Date(2026, 1, 9, 3, 0, 0, 0, time.UTC), false},
+		{Window{Days: []string{"fri"}, Start: "22:00", End: "04:00"}, time.Date(2026, 1, 9, 12, 0, 0, 0, time.UTC), false},
+		{Window{Start: "22:00", End: "04:00"}, time.Date(2026, 1, 9, 12, 0, 0, 0, time.UTC), false},
+		{Window{Start: "22:00", End: "04:00"}, time.Date(2026, 1, 9, 1, 0, 0, 0, time.UTC), true},
+	}
+
+	for _, test := range tests {
+		if test.window.Contains(test.t) != test.expected {
+			t.Errorf("Expected window %v to contain %s to be %t and it wasn't", test.window, test.t, test.expected)
+		}
+	}
+}
+
+func TestWindowContainsDaylightSavingTime(t *testing.T) {
+	loc, locErr := time.LoadLocation("America/Toronto")
+	if locErr != nil {
+		t.Errorf("%s", locErr.Error())
+		return
+	}
+
+	//Clocks are set forward from 02:00 to 03:00 on March 8, 2026
+	window := Window{Start: "09:00", End: "10:00"}
+	if !window.Contains(time.Date(2026, 3, 8, 9, 30, 0, 0, loc)) {
+		t.Errorf("Expected window to contain 09:30 on the day clocks are set forward and it didn't")
+	}
+
+	if window.Contains(time.Date(2026, 3, 8, 10, 30, 0, 0, loc)) {
+		t.Errorf("Expected window not to contain 10:30 on the day clocks are set forward and it did")
+	}
+}
+
+func TestBlackoutContains(t *testing.T) {
+	loc, locErr := time.LoadLocation("America/Toronto")
+	if locErr != nil {
+		t.Errorf("%s", locErr.Error())
+		return
+	}
+
+	tests := []struct {
+		blackout Blackout
+		t        time.Time
+		expected bool
+	}{
+		//Dates include the whole end day
+		{Blackout{Start: "2026-12-24", End: "2026-12-26"}, time.Date(2026, 12, 24, 0, 0, 0, 0, loc), true},
+		{Blackout{Start: "2026-12-24", End: "2026-12-26"}, time.Date(2026, 12, 26, 23, 59, 0, 0, loc), true},
+		{Blackout{Start: "2026-12-24", End: "2026-12-26"}, time.Date(2026, 12, 27, 0, 0, 0, 0, loc), false},
+		{Blackout{Start: "2026-12-24", End: "2026-12-26"}, time.Date(2026, 12, 23, 23, 59, 0, 0, loc), false},
+		//Times spanning midnight are interpreted in the given location
+		{Blackout{Start: "2026-12-31T22:00", End: "2027-01-01T02:00"}, time.Date(2026, 12, 31, 23, 30, 0, 0, loc), true},
+		{Blackout{Start: "2026-12-31T22:00", End: "2027-01-01T02:00"}, time.Date(2027, 1, 1, 1, 59, 0, 0, loc), true},
+		{Blackout{Start: "2026-12-31T22:00", End: "2027-01-01T02:00"}, time.Date(2027, 1, 1, 2, 0, 0, 0, loc), false},
+		{Blackout{Start: "2026-12-31T22:00", End: "2027-01-01T02:00"}, time.Date(2026, 12, 31, 22, 0, 0, 0, time.UTC), false},
+		{Blackout{Start: "2026-12-31T22:00", End: "2027-01-01T02:00"}, time.Date(2027, 1, 1, 3, 30, 0, 0, time.UTC), true},
+		//Rfc3339 times carry their own offset
+		{Blackout{Start: "2026-12-31T22:00:00Z", End: "2027-01-01T02:00:00Z"}, time.Date(2026, 12, 31, 22, 0, 0, 0, time.UTC), true},
+		{Blackout{Start: "2026-12-31T22:00:00Z", End: "2027-01-01T02:00:00Z"}, time.Date(2026, 12, 31, 22, 30, 0, 0, loc), false},
+		//Invalid blackouts contain nothing
+		{Blackout{Start: "2027-01-01", End: "2026-12-31"}, time.Date(2026, 12, 31, 12, 0, 0, 0, loc), false},
+	}
+
+	for _, test := range tests {
+		if test.blackout.Contains(test.t, loc) != test.expected {
+			t.Errorf("Expected blackout %v to contain %s to be %t and it wasn't", test.blackout, test.t, test.expected)
+		}
+	}
+}
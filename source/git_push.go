@@ -0,0 +1,66 @@
+package source
+
+import (
+	"net/url"
+	"strings"
+)
+
+type GitPush struct {
+	Urls          []string
+	Ref           string
+	DefaultBranch string
+}
+
+//Reduces the various forms a repository url can take (https, ssh, scp-like syntax) to a host and path
+func normalizeGitUrl(repoUrl string) string {
+	normalized := strings.TrimSpace(repoUrl)
+
+	if strings.Contains(normalized, "://") {
+		parsed, err := url.Parse(normalized)
+		if err == nil {
+			normalized = parsed.Hostname() + "/" + strings.TrimPrefix(parsed.Path, "/")
+		}
+	} else if strings.Contains(normalized, ":") {
+		parts := strings.SplitN(normalized, ":", 2)
+		host := parts[0]
+		if strings.Contains(host, "@") {
+			host = host[strings.LastIndex(host, "@")+1:]
+		}
+		normalized = host + "/" + strings.TrimPrefix(parts[1], "/")
+	}
+
+	normalized = strings.TrimSuffix(normalized, "/")
+	normalized = strings.TrimSuffix(normalized, ".git")
+	return strings.ToLower(normalized)
+}
+
+func (repo *GitRepo) MatchesPush(push GitPush) bool {
+	urlMatch := false
+	for _, pushUrl := range push.Urls {
+		if pushUrl != "" && normalizeGitUrl(pushUrl) == normalizeGitUrl(repo.Url) {
+			urlMatch = true
+			break
+		}
+	}
+
+	if !urlMatch {
+		return false
+	}
+
+	ref := repo.Ref
+	if ref == "" {
+		ref = push.DefaultBranch
+	}
+
+	return push.Ref == ref || push.Ref == "refs/heads/"+ref || push.Ref == "refs/tags/"+ref
+}
+
+func (srcs *Sources) MatchesGitPush(push GitPush) bool {
+	for _, src := range *srcs {
+		if src.GetType() == TypeGitRepo && src.GitRepo.MatchesPush(push) {
+			return true
+		}
+	}
+
+	return false
+}
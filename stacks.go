@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Ferlab-Ste-Justine/terracd/config"
+	"github.com/Ferlab-Ste-Justine/terracd/hook"
+	"github.com/Ferlab-Ste-Justine/terracd/metrics"
+)
+
+type stackOutcome struct {
+	result  hook.OpResult
+	code    int
+	blocked bool
+	done    chan struct{}
+}
+
+//Reports a stack that was not executed through its skip termination hook
+func skipStack(stack config.Stack, reason string, failedDeps []string) {
+	opInfo := hook.OpInfo{
+		"command": stack.Config.Command,
+		"result": hook.OpSkip.ToString(),
+		"stack": stack.Name,
+		"skip_reason": reason,
+	}
+	if len(failedDeps) > 0 {
+		opInfo["failed_dependencies"] = strings.Join(failedDeps, ",")
+	}
+
+	terminate(stack.Config, hook.OpSkip, opInfo, metrics.CommandInfo{
+		Command: stack.Config.Command,
+		Result: hook.OpSkip.ToString(),
+	}, []metrics.Provider{})
+}
+
+//Runs the stacks in dependency order, running independent stacks in parallel.
+//Stacks depending on a stack that failed or was not executed are skipped.
+func runStacks(ctx context.Context, conf config.Config) int {
+	sorted, sortErr := conf.Stacks.Sort()
+	if sortErr != nil {
+		fmt.Println(sortErr.Error())
+		return 1
+	}
+
+	outcomes := map[string]*stackOutcome{}
+	for _, stack := range sorted {
+		outcomes[stack.Name] = &stackOutcome{done: make(chan struct{})}
+	}
+
+	var wg sync.WaitGroup
+	for _, stack := range sorted {
+		wg.Add(1)
+		go func(stack config.Stack) {
+			defer wg.Done()
+			outcome := outcomes[stack.Name]
+			defer close(outcome.done)
+
+			failedDeps := []string{}
+			for _, dep := range stack.DependsOn {
+				depOutcome := outcomes[dep]
+				<-depOutcome.done
+				if depOutcome.blocked || depOutcome.result == hook.OpFailure {
+					failedDeps = append(failedDeps, dep)
+				}
+			}
+
+			if len(failedDeps) > 0 {
+				fmt.Printf("Warning: Skipping stack %s as the following stacks it depends on did not complete: %s\n", stack.Name, strings.Join(failedDeps, ", "))
+				outcome.blocked = true
+				skipStack(stack, "failed_dependencies", failedDeps)
+				return
+			}
+
+			if ctx.Err() != nil {
+				fmt.Printf("Warning: Skipping stack %s as the execution was cancelled.\n", stack.Name)
+				outcome.blocked = true
+				skipStack(stack, "cancelled", []string{})
+				return
+			}
+
+			fmt.Printf("Info: Running stack %s.\n", stack.Name)
+			outcome.result, outcome.code = runIteration(ctx, stack.Config, stack.Name)
+			fmt.Printf("Info: Stack %s completed with result %s.\n", stack.Name, outcome.result.ToString())
+		}(stack)
+	}
+	wg.Wait()
+
+	code := 0
+	for _, stack := range sorted {
+		outcome := outcomes[stack.Name]
+		if outcome.blocked || outcome.code != 0 {
+			code = 1
+		}
+	}
+
+	return code
+}
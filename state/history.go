@@ -0,0 +1,53 @@
+package state
+
+import (
+	"strings"
+	"time"
+
+	"github.com/Ferlab-Ste-Justine/terracd/source"
+)
+
+const maxRunErrorLength = 2000
+
+type Run struct {
+	Command      string
+	Result       string
+	StartedAt    time.Time           `yaml:"started_at"`
+	EndedAt      time.Time           `yaml:"ended_at"`
+	CommitHashes []source.CommitHash `yaml:"commit_hashes,omitempty"`
+	PlanChanges  map[string]int64    `yaml:"plan_changes,omitempty"`
+	Drift        string              `yaml:",omitempty"`
+	Targets      []string            `yaml:",omitempty"`
+	Replace      []string            `yaml:",omitempty"`
+	Error        string              `yaml:",omitempty"`
+}
+
+func NewRun(command string, result string, startedAt time.Time, err error) Run {
+	run := Run{
+		Command: command,
+		Result: result,
+		StartedAt: startedAt,
+		EndedAt: time.Now(),
+	}
+
+	if err != nil {
+		run.Error = strings.TrimSpace(err.Error())
+		if len(run.Error) > maxRunErrorLength {
+			run.Error = run.Error[:maxRunErrorLength] + "..."
+		}
+	}
+
+	return run
+}
+
+func (run *Run) GetDuration() time.Duration {
+	return run.EndedAt.Sub(run.StartedAt).Round(time.Second)
+}
+
+//Appends a run to the history, discarding the oldest runs beyond the given size
+func (st *State) AddRun(run Run, size int) {
+	st.History = append(st.History, run)
+	if len(st.History) > size {
+		st.History = st.History[len(st.History)-size:]
+	}
+}
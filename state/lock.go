@@ -0,0 +1,97 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+const lockRetryInterval = 2 * time.Second
+
+type LockConfig struct {
+	Ttl     time.Duration
+	Timeout time.Duration
+}
+
+//Duration after which a lock that is not renewed, because the execution holding it crashed, expires
+func (conf *LockConfig) GetTtl() time.Duration {
+	if conf.Ttl == 0 {
+		return 5 * time.Minute
+	}
+
+	return conf.Ttl
+}
+
+//Duration to wait for a lock held by another execution to be released before failing
+func (conf *LockConfig) GetTimeout() time.Duration {
+	if conf.Timeout == 0 {
+		return 30 * time.Second
+	}
+
+	return conf.Timeout
+}
+
+type LockInfo struct {
+	Holder     string
+	AcquiredAt time.Time `yaml:"acquired_at"`
+	ExpiresAt  time.Time `yaml:"expires_at"`
+}
+
+func newLockInfo(conf LockConfig) LockInfo {
+	hostname, hostErr := os.Hostname()
+	if hostErr != nil {
+		hostname = "unknown"
+	}
+
+	now := time.Now()
+	return LockInfo{
+		Holder: fmt.Sprintf("%s (pid %d)", hostname, os.Getpid()),
+		AcquiredAt: now,
+		ExpiresAt: now.Add(conf.GetTtl()),
+	}
+}
+
+func (info *LockInfo) IsExpired() bool {
+	return time.Now().After(info.ExpiresAt)
+}
+
+func (info *LockInfo) ToString() string {
+	return fmt.Sprintf("%s since %s", info.Holder, info.AcquiredAt.Format(time.RFC3339))
+}
+
+//Periodically renews a lock for as long as it is held
+type heartbeat struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+//Calls the renewal function at the given interval until the heartbeat is stopped or the renewal function returns false
+func startHeartbeat(interval time.Duration, renew func() bool) *heartbeat {
+	ctx, cancel := context.WithCancel(context.Background())
+	hb := &heartbeat{cancel: cancel, done: make(chan struct{})}
+
+	go func() {
+		defer close(hb.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if !renew() {
+					return
+				}
+			}
+		}
+	}()
+
+	return hb
+}
+
+func (hb *heartbeat) Stop() {
+	hb.cancel()
+	<-hb.done
+}
@@ -1,21 +1,66 @@
 package state
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"path"
+	"sync/atomic"
+	"time"
 
 	"github.com/Ferlab-Ste-Justine/terracd/cache"
 	"github.com/Ferlab-Ste-Justine/terracd/fs"
 	"github.com/Ferlab-Ste-Justine/terracd/recurrence"
+	"github.com/Ferlab-Ste-Justine/terracd/source"
 )
 
+type PendingPlan struct {
+	Hash         string
+	CommitHashes []source.CommitHash `yaml:"commit_hashes"`
+	Fingerprint  string
+	Targets      []string
+	Replace      []string
+	Timestamp    time.Time
+}
+
+func (pending *PendingPlan) IsDefined() bool {
+	return pending.Hash != ""
+}
+
+//Returns the sources and options the plan was produced from
+func (pending *PendingPlan) GetOccurrence() recurrence.Occurrence {
+	return recurrence.Occurrence{
+		CommitHashes: pending.CommitHashes,
+		Fingerprint: pending.Fingerprint,
+		Targets: pending.Targets,
+		Replace: pending.Replace,
+	}
+}
+
+type Approval struct {
+	PlanHash string `yaml:"plan_hash"`
+}
+
 type State struct {
 	LastCommandOccurrence recurrence.CommandOccurrence `yaml:"last_command_occurrence"`
 	CacheInfo cache.ProviderCacheInfo				   `yaml:"cache_info"`
+	PendingPlan PendingPlan                            `yaml:"pending_plan"`
+	History []Run                                      `yaml:"history"`
+	Failures recurrence.Failures                       `yaml:"failures"`
 }
 
-type StateScopedFn func(State) (State, error)
+type StateScopedFn func(context.Context, State, StateStore) (State, error)
 
-func WrapInState(fn StateScopedFn, conf StateStoreConfig, paths fs.Paths) error {
+//Runs the function with the state, locking it for the duration of the function.
+//The state returned by the function is persisted even if it returns an error, so that failures can be recorded.
+//The function should return the state it was given, with the record of its failure, if its other changes should be discarded.
+//If the lock is lost while the function runs, the context passed to the function is cancelled and the state is not persisted
+//as another execution may have modified it in the meantime.
+func WrapInState(ctx context.Context, fn StateScopedFn, conf StateStoreConfig, paths fs.Paths) error {
+	fnCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var lockLost atomic.Bool
 	var st State
 	var stateErr error
 	var store StateStore
@@ -33,23 +78,100 @@ func WrapInState(fn StateScopedFn, conf StateStoreConfig, paths fs.Paths) error
 
 		defer store.Cleanup()
 
+		lockErr := store.Lock(conf.Lock, func() {
+			lockLost.Store(true)
+			cancel()
+		})
+		if lockErr != nil {
+			return lockErr
+		}
+
+		defer func() {
+			unlockErr := store.Unlock()
+			if unlockErr != nil {
+				fmt.Printf("Warning: %s\n", unlockErr.Error())
+			}
+		}()
+
 		st, stateErr = store.Read()
 		if stateErr != nil {
 			return stateErr
 		}
 	}
 
-	newSt, fnErr := fn(st)
-	if fnErr != nil {
-		return fnErr
+	newSt, fnErr := fn(fnCtx, st, store)
+
+	if lockLost.Load() {
+		if fnErr != nil {
+			return errors.New(fmt.Sprintf("The lock on the state was lost during the execution and the state was not updated. The execution failed with: %s", fnErr.Error()))
+		}
+
+		return errors.New("The lock on the state was lost during the execution and the state was not updated")
 	}
 
 	if conf.IsDefined() {
 		writeErr := store.Write(newSt)
 		if writeErr != nil {
+			if fnErr != nil {
+				fmt.Printf("Warning: Failed to record the failure in the state: %s\n", writeErr.Error())
+				return fnErr
+			}
+
 			return writeErr
 		}
 	}
 
-	return nil
-}
\ No newline at end of file
+	return fnErr
+}
+
+func getInitializedStore(conf StateStoreConfig, paths fs.Paths) (StateStore, error) {
+	if !conf.IsDefined() {
+		return nil, errors.New("Cannot read the state as no state store is defined")
+	}
+
+	store, storeErr := conf.GetStore(path.Join(paths.FsStore, "state.yml"))
+	if storeErr != nil {
+		return nil, storeErr
+	}
+
+	initErr := store.Initialize()
+	if initErr != nil {
+		return nil, initErr
+	}
+
+	return store, nil
+}
+
+func ReadState(conf StateStoreConfig, paths fs.Paths) (State, error) {
+	store, storeErr := getInitializedStore(conf, paths)
+	if storeErr != nil {
+		return State{}, storeErr
+	}
+
+	defer store.Cleanup()
+
+	return store.Read()
+}
+
+func ReadStoreObject(conf StateStoreConfig, paths fs.Paths, name string) ([]byte, bool, error) {
+	store, storeErr := getInitializedStore(conf, paths)
+	if storeErr != nil {
+		return nil, false, storeErr
+	}
+
+	defer store.Cleanup()
+
+	return store.ReadObject(name)
+}
+
+//Releases the lock on the state regardless of the execution holding it
+func ForceUnlock(conf StateStoreConfig, paths fs.Paths) error {
+	store, storeErr := getInitializedStore(conf, paths)
+	if storeErr != nil {
+		return storeErr
+	}
+
+	defer store.Cleanup()
+
+	return store.ForceUnlock()
+}
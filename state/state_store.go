@@ -10,6 +10,13 @@ type StateStore interface {
 	Initialize() error
 	Read() (State, error)
 	Write(State) error
+	ReadObject(name string) ([]byte, bool, error)
+	WriteObject(name string, data []byte) error
+	DeleteObject(name string) error
+	//The onLost callback is called if the lock is lost while it is held
+	Lock(conf LockConfig, onLost func()) error
+	Unlock() error
+	ForceUnlock() error
 	Cleanup() error
 }
 
@@ -17,6 +24,7 @@ type StateStoreConfig struct {
 	Fs   FsConfig
 	Etcd EtcdConfig
 	S3   s3.S3ClientConfig
+	Lock LockConfig
 }
 
 func (conf *StateStoreConfig) IsDefined() bool {
@@ -4,9 +4,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/Ferlab-Ste-Justine/etcd-sdk/client"
+	clientv3 "go.etcd.io/etcd/client/v3"
 	yaml "gopkg.in/yaml.v2"
 
 	"github.com/Ferlab-Ste-Justine/terracd/auth"
@@ -27,8 +29,11 @@ func (conf *EtcdConfig) IsDefined() bool {
 }
 
 type EtcdStateStore struct {
-	Config EtcdConfig
-	client *client.EtcdClient
+	Config    EtcdConfig
+	client    *client.EtcdClient
+	heartbeat *heartbeat
+	lease     clientv3.LeaseID
+	leaseLost *atomic.Bool
 }
 
 func (store *EtcdStateStore) Initialize() error {
@@ -88,6 +93,155 @@ func (store *EtcdStateStore) Write(state State) error {
 	return nil
 }
 
+func (store *EtcdStateStore) ReadObject(name string) ([]byte, bool, error) {
+	keyInfo, err := store.client.GetKey(fmt.Sprintf("%s%s", store.Config.Prefix, name), client.GetKeyOptions{})
+	if err != nil {
+		return []byte{}, false, errors.New(fmt.Sprintf("Error retrieving object %s: %s", name, err.Error()))
+	}
+
+	if !keyInfo.Found() {
+		return []byte{}, false, nil
+	}
+
+	return []byte(keyInfo.Value), true, nil
+}
+
+func (store *EtcdStateStore) WriteObject(name string, data []byte) error {
+	_, err := store.client.PutKey(fmt.Sprintf("%s%s", store.Config.Prefix, name), string(data))
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error writing object %s: %s", name, err.Error()))
+	}
+
+	return nil
+}
+
+func (store *EtcdStateStore) DeleteObject(name string) error {
+	err := store.client.DeleteKey(fmt.Sprintf("%s%s", store.Config.Prefix, name))
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error deleting object %s: %s", name, err.Error()))
+	}
+
+	return nil
+}
+
+//Calls the function with the value of the object each time it is written. Blocks until the watch fails.
+func (store *EtcdStateStore) WatchObject(name string, fn func([]byte)) error {
+	key := fmt.Sprintf("%s%s", store.Config.Prefix, name)
+	for notif := range store.client.Watch(key, client.WatchOptions{}) {
+		if notif.Error != nil {
+			return notif.Error
+		}
+
+		if info, ok := notif.Changes.Upserts[key]; ok {
+			fn([]byte(info.Value))
+		}
+	}
+
+	return errors.New(fmt.Sprintf("Watch on object %s was closed", name))
+}
+
+func (store *EtcdStateStore) getLockKey() string {
+	return fmt.Sprintf("%s%s", store.Config.Prefix, "lock")
+}
+
+func (store *EtcdStateStore) Lock(conf LockConfig, onLost func()) error {
+	ttl := int64(conf.GetTtl().Seconds())
+	if ttl < 1 {
+		ttl = 1
+	}
+
+	lock, timeout, err := store.client.AcquireLock(client.AcquireLockOptions{
+		Key: store.getLockKey(),
+		Ttl: ttl,
+		Timeout: conf.GetTimeout(),
+		RetryInterval: lockRetryInterval,
+	})
+	if err != nil {
+		if timeout {
+			return errors.New(fmt.Sprintf("Could not acquire the lock on the state as it is held by another execution. If that execution crashed, the lock will expire after %s or it can be released with the unlock command.", conf.GetTtl().String()))
+		}
+
+		return errors.New(fmt.Sprintf("Error acquiring the lock on the state: %s", err.Error()))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	keepAlive, keepAliveErr := store.client.Client.KeepAlive(ctx, lock.Lease)
+	if keepAliveErr != nil {
+		cancel()
+		store.revokeLease(lock.Lease)
+		return errors.New(fmt.Sprintf("Error keeping the lock on the state alive: %s", keepAliveErr.Error()))
+	}
+
+	leaseLost := &atomic.Bool{}
+	hb := &heartbeat{cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(hb.done)
+		for range keepAlive {
+		}
+
+		if ctx.Err() == nil {
+			leaseLost.Store(true)
+			fmt.Println("Warning: The lease of the lock on the state could not be renewed. Aborting the execution as another execution may acquire it.")
+			onLost()
+		}
+	}()
+	store.heartbeat = hb
+	store.lease = lock.Lease
+	store.leaseLost = leaseLost
+
+	return nil
+}
+
+//Revokes the given lease only, as the lock key may since have been acquired by another execution under another lease
+func (store *EtcdStateStore) revokeLease(lease clientv3.LeaseID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), store.client.RequestTimeout)
+	defer cancel()
+
+	_, err := store.client.Client.Revoke(ctx, lease)
+	return err
+}
+
+func (store *EtcdStateStore) Unlock() error {
+	if store.heartbeat == nil {
+		return nil
+	}
+
+	store.heartbeat.Stop()
+	store.heartbeat = nil
+
+	if store.leaseLost.Load() {
+		fmt.Println("Warning: The lease of the lock on the state was lost. Not releasing the lock as it may be held by another execution.")
+		return nil
+	}
+
+	err := store.revokeLease(store.lease)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error releasing the lock on the state: %s", err.Error()))
+	}
+
+	return nil
+}
+
+func (store *EtcdStateStore) ForceUnlock() error {
+	keyInfo, err := store.client.GetKey(store.getLockKey(), client.GetKeyOptions{})
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error retrieving the lock on the state: %s", err.Error()))
+	}
+
+	if !keyInfo.Found() {
+		fmt.Println("Info: The state is not locked.")
+		return nil
+	}
+
+	err = store.client.ReleaseLock(store.getLockKey())
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error releasing the lock on the state: %s", err.Error()))
+	}
+
+	fmt.Println("Info: Released the lock on the state.")
+	return nil
+}
+
 func (store *EtcdStateStore) Cleanup() error {
 	store.client.Close()
 	return nil
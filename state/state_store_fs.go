@@ -5,6 +5,9 @@ import (
 	"fmt"
 	yaml "gopkg.in/yaml.v2"
 	"io/ioutil"
+	"os"
+	"path"
+	"time"
 
 	"github.com/Ferlab-Ste-Justine/terracd/fs"
 )
@@ -19,7 +22,8 @@ func (conf *FsConfig) IsDefined() bool {
 }
 
 type FsStateStore struct {
-	Config FsConfig
+	Config   FsConfig
+	lockFile *os.File
 }
 
 func (store *FsStateStore) Initialize() error {
@@ -69,6 +73,155 @@ func (store *FsStateStore) Write(state State) error {
 	return nil
 }
 
+func (store *FsStateStore) getObjectPath(name string) string {
+	return path.Join(path.Dir(store.Config.Path), name)
+}
+
+func (store *FsStateStore) ReadObject(name string) ([]byte, bool, error) {
+	objPath := store.getObjectPath(name)
+
+	objExists, objExistsErr := fs.PathExists(objPath)
+	if objExistsErr != nil {
+		return []byte{}, false, objExistsErr
+	}
+	if !objExists {
+		return []byte{}, false, nil
+	}
+
+	data, err := ioutil.ReadFile(objPath)
+	if err != nil {
+		return []byte{}, false, errors.New(fmt.Sprintf("Error reading the object file %s: %s", objPath, err.Error()))
+	}
+
+	return data, true, nil
+}
+
+func (store *FsStateStore) WriteObject(name string, data []byte) error {
+	objPath := store.getObjectPath(name)
+
+	err := fs.EnsureContainingDirExists(objPath)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error creating the object file directory: %s", err.Error()))
+	}
+
+	err = ioutil.WriteFile(objPath, data, 0600)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error writing the object file %s: %s", objPath, err.Error()))
+	}
+
+	return nil
+}
+
+func (store *FsStateStore) DeleteObject(name string) error {
+	return fs.EnsureFileNotExists(store.getObjectPath(name))
+}
+
+func (store *FsStateStore) getLockPath() string {
+	return path.Join(path.Dir(store.Config.Path), "state.lock")
+}
+
+//Locks the state with an exclusive lock on a file which the operating system releases if the process crashes.
+//The lock therefore has no expiry, the ttl is not used and the lock cannot be lost.
+func (store *FsStateStore) Lock(conf LockConfig, onLost func()) error {
+	lockPath := store.getLockPath()
+
+	err := fs.EnsureContainingDirExists(lockPath)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error creating the lock file directory: %s", err.Error()))
+	}
+
+	file, openErr := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0600)
+	if openErr != nil {
+		return errors.New(fmt.Sprintf("Error opening the lock file: %s", openErr.Error()))
+	}
+
+	deadline := time.Now().Add(conf.GetTimeout())
+	for {
+		locked, lockErr := tryLockFile(file)
+		if lockErr != nil {
+			file.Close()
+			return errors.New(fmt.Sprintf("Error acquiring the lock on the state: %s", lockErr.Error()))
+		}
+
+		if locked {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			holder, _ := ioutil.ReadAll(file)
+			file.Close()
+			return errors.New(fmt.Sprintf("Could not acquire the lock on the state as it is held by %s", string(holder)))
+		}
+
+		time.Sleep(lockRetryInterval)
+	}
+
+	info := newLockInfo(conf)
+	holderErr := file.Truncate(0)
+	if holderErr == nil {
+		_, holderErr = file.WriteAt([]byte(info.ToString()), 0)
+	}
+	if holderErr != nil {
+		fmt.Printf("Warning: Failed to record the holder of the lock on the state: %s\n", holderErr.Error())
+	}
+
+	store.lockFile = file
+	return nil
+}
+
+func (store *FsStateStore) Unlock() error {
+	if store.lockFile == nil {
+		return nil
+	}
+
+	unlockErr := unlockFile(store.lockFile)
+	closeErr := store.lockFile.Close()
+	store.lockFile = nil
+
+	if unlockErr != nil {
+		return errors.New(fmt.Sprintf("Error releasing the lock on the state: %s", unlockErr.Error()))
+	}
+
+	return closeErr
+}
+
+//Removes the lock file. As the filesystem lock is released when the process holding it terminates,
+//this is only needed to recover from a lock file that became unusable.
+func (store *FsStateStore) ForceUnlock() error {
+	lockPath := store.getLockPath()
+
+	exists, existsErr := fs.PathExists(lockPath)
+	if existsErr != nil {
+		return existsErr
+	}
+
+	if exists {
+		file, openErr := os.OpenFile(lockPath, os.O_RDWR, 0600)
+		if openErr != nil {
+			return errors.New(fmt.Sprintf("Error opening the lock file: %s", openErr.Error()))
+		}
+		defer file.Close()
+
+		locked, lockErr := tryLockFile(file)
+		if lockErr != nil {
+			return errors.New(fmt.Sprintf("Error checking the lock on the state: %s", lockErr.Error()))
+		}
+
+		if locked {
+			unlockFile(file)
+			exists = false
+		}
+	}
+
+	if !exists {
+		fmt.Println("Info: The state is not locked.")
+		return nil
+	}
+
+	fmt.Println("Warning: Locks on a filesystem state store are released automatically when the execution holding them terminates. Removing the lock file anyways.")
+	return fs.EnsureFileNotExists(lockPath)
+}
+
 func (store *FsStateStore) Cleanup() error {
 	return nil
 }
\ No newline at end of file
@@ -6,7 +6,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"path"
+	"time"
 
 	minio "github.com/minio/minio-go/v7"
 	yaml "gopkg.in/yaml.v2"
@@ -15,7 +17,10 @@ import (
 )
 
 type S3StateStore struct {
-	Config s3.S3ClientConfig
+	Config     s3.S3ClientConfig
+	lockEtag   string
+	lockExpiry time.Time
+	heartbeat  *heartbeat
 }
 
 func (store *S3StateStore) Initialize() error {
@@ -80,6 +85,277 @@ func (store *S3StateStore) Write(state State) error {
 	return putErr
 }
 
+func (store *S3StateStore) ReadObject(name string) ([]byte, bool, error) {
+	conn, connErr := s3.Connect(store.Config)
+	if connErr != nil {
+		return []byte{}, false, connErr
+	}
+
+	exists, existsErr := s3.KeyExists(store.Config.Bucket, path.Join(store.Config.Path, name), conn)
+	if existsErr != nil {
+		return []byte{}, false, existsErr
+	}
+
+	if !exists {
+		return []byte{}, false, nil
+	}
+
+	objRead, readErr := conn.GetObject(context.Background(), store.Config.Bucket, path.Join(store.Config.Path, name), minio.GetObjectOptions{})
+	if readErr != nil {
+		return []byte{}, false, readErr
+	}
+
+	data, transfErr := io.ReadAll(objRead)
+	if transfErr != nil {
+		return []byte{}, false, transfErr
+	}
+
+	return data, true, nil
+}
+
+func (store *S3StateStore) WriteObject(name string, data []byte) error {
+	conn, connErr := s3.Connect(store.Config)
+	if connErr != nil {
+		return connErr
+	}
+
+	_, putErr := conn.PutObject(
+		context.Background(),
+		store.Config.Bucket,
+		path.Join(store.Config.Path, name),
+		bytes.NewReader(data),
+		int64(len(data)),
+		minio.PutObjectOptions{},
+	)
+
+	return putErr
+}
+
+func (store *S3StateStore) DeleteObject(name string) error {
+	conn, connErr := s3.Connect(store.Config)
+	if connErr != nil {
+		return connErr
+	}
+
+	return conn.RemoveObject(context.Background(), store.Config.Bucket, path.Join(store.Config.Path, name), minio.RemoveObjectOptions{})
+}
+
+func (store *S3StateStore) getLockKey() string {
+	return path.Join(store.Config.Path, "lock.yml")
+}
+
+func isPreconditionFailed(err error) bool {
+	return minio.ToErrorResponse(err).StatusCode == http.StatusPreconditionFailed
+}
+
+//Writes the lock object, either if it does not exist or if its etag matches the given one
+func (store *S3StateStore) putLock(conn *minio.Client, info LockInfo, matchEtag string) (string, error) {
+	output, err := yaml.Marshal(&info)
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("Error serializing the lock info: %s", err.Error()))
+	}
+
+	opts := minio.PutObjectOptions{}
+	if matchEtag == "" {
+		opts.SetMatchETagExcept("*")
+	} else {
+		opts.SetMatchETag(matchEtag)
+	}
+
+	upload, putErr := conn.PutObject(
+		context.Background(),
+		store.Config.Bucket,
+		store.getLockKey(),
+		bytes.NewReader(output),
+		int64(len(output)),
+		opts,
+	)
+	if putErr != nil {
+		return "", putErr
+	}
+
+	return upload.ETag, nil
+}
+
+func (store *S3StateStore) readLock(conn *minio.Client) (LockInfo, string, bool, error) {
+	var info LockInfo
+
+	exists, existsErr := s3.KeyExists(store.Config.Bucket, store.getLockKey(), conn)
+	if existsErr != nil || !exists {
+		return info, "", false, existsErr
+	}
+
+	objRead, readErr := conn.GetObject(context.Background(), store.Config.Bucket, store.getLockKey(), minio.GetObjectOptions{})
+	if readErr != nil {
+		return info, "", false, readErr
+	}
+
+	data, transfErr := io.ReadAll(objRead)
+	if transfErr != nil {
+		return info, "", false, transfErr
+	}
+
+	objInfo, statErr := objRead.Stat()
+	if statErr != nil {
+		return info, "", false, statErr
+	}
+
+	unmarErr := yaml.Unmarshal(data, &info)
+	if unmarErr != nil {
+		return info, "", false, errors.New(fmt.Sprintf("Error deserializing the lock info: %s", unmarErr.Error()))
+	}
+
+	return info, objInfo.ETag, true, nil
+}
+
+//Attempts to create the lock object or to take over an expired one.
+//Returns the current holder of the lock if it could not be acquired.
+func (store *S3StateStore) tryLock(conn *minio.Client, info LockInfo) (string, LockInfo, error) {
+	etag, putErr := store.putLock(conn, info, "")
+	if putErr == nil {
+		return etag, info, nil
+	}
+
+	if !isPreconditionFailed(putErr) {
+		return "", LockInfo{}, putErr
+	}
+
+	current, currentEtag, exists, readErr := store.readLock(conn)
+	if readErr != nil {
+		return "", LockInfo{}, readErr
+	}
+
+	if !exists {
+		return "", LockInfo{}, nil
+	}
+
+	if !current.IsExpired() {
+		return "", current, nil
+	}
+
+	fmt.Printf("Info: Taking over the lock on the state held by %s which expired at %s.\n", current.Holder, current.ExpiresAt.Format(time.RFC3339))
+	etag, putErr = store.putLock(conn, info, currentEtag)
+	if putErr != nil {
+		if isPreconditionFailed(putErr) {
+			return "", current, nil
+		}
+
+		return "", LockInfo{}, putErr
+	}
+
+	return etag, info, nil
+}
+
+func (store *S3StateStore) Lock(conf LockConfig, onLost func()) error {
+	conn, connErr := s3.Connect(store.Config)
+	if connErr != nil {
+		return connErr
+	}
+
+	deadline := time.Now().Add(conf.GetTimeout())
+	for {
+		info := newLockInfo(conf)
+		etag, holder, lockErr := store.tryLock(conn, info)
+		if lockErr != nil {
+			return errors.New(fmt.Sprintf("Error acquiring the lock on the state: %s", lockErr.Error()))
+		}
+
+		if etag != "" {
+			store.lockEtag = etag
+			store.lockExpiry = info.ExpiresAt
+			break
+		}
+
+		if time.Now().After(deadline) {
+			return errors.New(fmt.Sprintf("Could not acquire the lock on the state as it is held by %s. If that execution crashed, the lock will expire at %s or it can be released with the unlock command.", holder.ToString(), holder.ExpiresAt.Format(time.RFC3339)))
+		}
+
+		time.Sleep(lockRetryInterval)
+	}
+
+	store.heartbeat = startHeartbeat(conf.GetTtl() / 3, func() bool {
+		info := newLockInfo(conf)
+		etag, putErr := store.putLock(conn, info, store.lockEtag)
+		if putErr != nil {
+			if isPreconditionFailed(putErr) {
+				fmt.Println("Warning: The lock on the state was released or taken over by another execution. Aborting the execution.")
+				onLost()
+				return false
+			}
+
+			if time.Now().After(store.lockExpiry) {
+				fmt.Printf("Warning: Failed to renew the lock on the state before it expired: %s. Aborting the execution as another execution may acquire it.\n", putErr.Error())
+				onLost()
+				return false
+			}
+
+			fmt.Printf("Warning: Failed to renew the lock on the state: %s\n", putErr.Error())
+			return true
+		}
+
+		store.lockEtag = etag
+		store.lockExpiry = info.ExpiresAt
+		return true
+	})
+
+	return nil
+}
+
+func (store *S3StateStore) Unlock() error {
+	if store.heartbeat == nil {
+		return nil
+	}
+
+	store.heartbeat.Stop()
+	store.heartbeat = nil
+
+	conn, connErr := s3.Connect(store.Config)
+	if connErr != nil {
+		return connErr
+	}
+
+	_, etag, exists, readErr := store.readLock(conn)
+	if readErr != nil {
+		return errors.New(fmt.Sprintf("Error releasing the lock on the state: %s", readErr.Error()))
+	}
+
+	if !exists || etag != store.lockEtag {
+		return errors.New("Error releasing the lock on the state: it was released or taken over by another execution")
+	}
+
+	removeErr := conn.RemoveObject(context.Background(), store.Config.Bucket, store.getLockKey(), minio.RemoveObjectOptions{})
+	if removeErr != nil {
+		return errors.New(fmt.Sprintf("Error releasing the lock on the state: %s", removeErr.Error()))
+	}
+
+	return nil
+}
+
+func (store *S3StateStore) ForceUnlock() error {
+	conn, connErr := s3.Connect(store.Config)
+	if connErr != nil {
+		return connErr
+	}
+
+	info, _, exists, readErr := store.readLock(conn)
+	if readErr != nil {
+		return errors.New(fmt.Sprintf("Error retrieving the lock on the state: %s", readErr.Error()))
+	}
+
+	if !exists {
+		fmt.Println("Info: The state is not locked.")
+		return nil
+	}
+
+	removeErr := conn.RemoveObject(context.Background(), store.Config.Bucket, store.getLockKey(), minio.RemoveObjectOptions{})
+	if removeErr != nil {
+		return errors.New(fmt.Sprintf("Error releasing the lock on the state: %s", removeErr.Error()))
+	}
+
+	fmt.Printf("Info: Released the lock on the state held by %s.\n", info.ToString())
+	return nil
+}
+
 func (store *S3StateStore) Cleanup() error {
 	return nil
 }
\ No newline at end of file
@@ -0,0 +1,131 @@
+package terraform
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+type ChangeBudget struct {
+	MaxDeletions      *int64   `yaml:"max_deletions"`
+	MaxReplacements   *int64   `yaml:"max_replacements"`
+	MaxChanges        *int64   `yaml:"max_changes"`
+	MaxChangesPercent *float64 `yaml:"max_changes_percent"`
+}
+
+func (budget *ChangeBudget) IsDefined() bool {
+	return budget.MaxDeletions != nil || budget.MaxReplacements != nil || budget.MaxChanges != nil || budget.MaxChangesPercent != nil
+}
+
+func (budget *ChangeBudget) Validate() error {
+	for _, max := range []*int64{budget.MaxDeletions, budget.MaxReplacements, budget.MaxChanges} {
+		if max != nil && *max < 0 {
+			return errors.New("Change budget maximums cannot be negative")
+		}
+	}
+
+	if budget.MaxChangesPercent != nil && (*budget.MaxChangesPercent < 0 || *budget.MaxChangesPercent > 100) {
+		return errors.New("Change budget max_changes_percent must be between 0 and 100")
+	}
+
+	return nil
+}
+
+func minInt64(first *int64, second *int64) *int64 {
+	if first == nil {
+		return second
+	}
+
+	if second == nil || *first < *second {
+		return first
+	}
+
+	return second
+}
+
+func minFloat64(first *float64, second *float64) *float64 {
+	if first == nil {
+		return second
+	}
+
+	if second == nil || *first < *second {
+		return first
+	}
+
+	return second
+}
+
+func (budget *ChangeBudget) Merge(other ChangeBudget) ChangeBudget {
+	return ChangeBudget{
+		MaxDeletions: minInt64(budget.MaxDeletions, other.MaxDeletions),
+		MaxReplacements: minInt64(budget.MaxReplacements, other.MaxReplacements),
+		MaxChanges: minInt64(budget.MaxChanges, other.MaxChanges),
+		MaxChangesPercent: minFloat64(budget.MaxChangesPercent, other.MaxChangesPercent),
+	}
+}
+
+func countModuleResources(module *tfjson.StateModule) int64 {
+	if module == nil {
+		return 0
+	}
+
+	count := int64(0)
+	for _, resource := range module.Resources {
+		if resource.Mode == tfjson.ManagedResourceMode {
+			count += 1
+		}
+	}
+
+	for _, child := range module.ChildModules {
+		count += countModuleResources(child)
+	}
+
+	return count
+}
+
+func countStateResources(plan *tfjson.Plan) int64 {
+	if plan.PriorState == nil || plan.PriorState.Values == nil {
+		return 0
+	}
+
+	return countModuleResources(plan.PriorState.Values.RootModule)
+}
+
+func CheckChangeBudget(plan *tfjson.Plan, summary PlanSummary, budget ChangeBudget) error {
+	if !budget.IsDefined() {
+		return nil
+	}
+
+	totals := summary.Totals
+	excesses := []string{}
+
+	if budget.MaxDeletions != nil && totals.Delete > *budget.MaxDeletions {
+		excesses = append(excesses, fmt.Sprintf("%d deletions (max %d)", totals.Delete, *budget.MaxDeletions))
+	}
+
+	if budget.MaxReplacements != nil && totals.Replace > *budget.MaxReplacements {
+		excesses = append(excesses, fmt.Sprintf("%d replacements (max %d)", totals.Replace, *budget.MaxReplacements))
+	}
+
+	changes := totals.Create + totals.Update + totals.Delete + totals.Replace
+	if budget.MaxChanges != nil && changes > *budget.MaxChanges {
+		excesses = append(excesses, fmt.Sprintf("%d changes (max %d)", changes, *budget.MaxChanges))
+	}
+
+	stateResources := countStateResources(plan)
+	if budget.MaxChangesPercent != nil && stateResources > 0 {
+		existingChanges := totals.Update + totals.Delete + totals.Replace
+		percent := float64(existingChanges) * 100 / float64(stateResources)
+		if percent > *budget.MaxChangesPercent {
+			excesses = append(excesses, fmt.Sprintf("%d of %d existing resources changed, %.1f%% (max %.1f%%)", existingChanges, stateResources, percent, *budget.MaxChangesPercent))
+		}
+	}
+
+	if len(excesses) > 0 {
+		return errors.New(fmt.Sprintf("Aborting as the plan exceeds the change budget: %s", strings.Join(excesses, ", ")))
+	}
+
+	return nil
+}
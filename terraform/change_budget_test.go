@@ -0,0 +1,121 @@
+package terraform
+
+import (
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+func int64Ptr(value int64) *int64 {
+	return &value
+}
+
+func float64Ptr(value float64) *float64 {
+	return &value
+}
+
+//Returns a plan whose prior state contains the given number of managed resources, split between the root module and a child module
+func getBudgetTestPlan(resources int) *tfjson.Plan {
+	root := &tfjson.StateModule{}
+	child := &tfjson.StateModule{Address: "module.child"}
+	root.ChildModules = []*tfjson.StateModule{child}
+
+	for idx := 0; idx < resources; idx++ {
+		module := root
+		if idx % 2 == 1 {
+			module = child
+		}
+		module.Resources = append(module.Resources, &tfjson.StateResource{Mode: tfjson.ManagedResourceMode})
+	}
+	root.Resources = append(root.Resources, &tfjson.StateResource{Mode: tfjson.DataResourceMode})
+
+	return &tfjson.Plan{
+		PriorState: &tfjson.State{
+			Values: &tfjson.StateValues{RootModule: root},
+		},
+	}
+}
+
+func TestCheckChangeBudget(t *testing.T) {
+	plan := getBudgetTestPlan(10)
+
+	tests := []struct {
+		name     string
+		totals   PlanTotals
+		budget   ChangeBudget
+		accepted bool
+	}{
+		{"undefined budget", PlanTotals{Delete: 100}, ChangeBudget{}, true},
+		{"deletions at the maximum", PlanTotals{Delete: 2}, ChangeBudget{MaxDeletions: int64Ptr(2)}, true},
+		{"deletions over the maximum", PlanTotals{Delete: 3}, ChangeBudget{MaxDeletions: int64Ptr(2)}, false},
+		{"no deletions allowed", PlanTotals{Delete: 1}, ChangeBudget{MaxDeletions: int64Ptr(0)}, false},
+		{"no deletions with none allowed", PlanTotals{Create: 5}, ChangeBudget{MaxDeletions: int64Ptr(0)}, true},
+		{"replacements at the maximum", PlanTotals{Replace: 1}, ChangeBudget{MaxReplacements: int64Ptr(1)}, true},
+		{"replacements over the maximum", PlanTotals{Replace: 2}, ChangeBudget{MaxReplacements: int64Ptr(1)}, false},
+		{"changes at the maximum", PlanTotals{Create: 1, Update: 1, Delete: 1, Replace: 1}, ChangeBudget{MaxChanges: int64Ptr(4)}, true},
+		{"changes over the maximum", PlanTotals{Create: 2, Update: 1, Delete: 1, Replace: 1}, ChangeBudget{MaxChanges: int64Ptr(4)}, false},
+		{"changed percent at the maximum", PlanTotals{Update: 1, Delete: 1}, ChangeBudget{MaxChangesPercent: float64Ptr(20)}, true},
+		{"changed percent over the maximum", PlanTotals{Update: 2, Delete: 1}, ChangeBudget{MaxChangesPercent: float64Ptr(20)}, false},
+		{"creations do not count in the changed percent", PlanTotals{Create: 50, Update: 2}, ChangeBudget{MaxChangesPercent: float64Ptr(20)}, true},
+		{"one exceeded maximum among several", PlanTotals{Delete: 1, Replace: 3}, ChangeBudget{MaxDeletions: int64Ptr(1), MaxReplacements: int64Ptr(2)}, false},
+	}
+
+	for _, test := range tests {
+		err := CheckChangeBudget(plan, PlanSummary{Totals: test.totals}, test.budget)
+		if test.accepted && err != nil {
+			t.Errorf("Expected plan with %s to be accepted and it wasn't: %s", test.name, err.Error())
+		}
+		if !test.accepted && err == nil {
+			t.Errorf("Expected plan with %s to be rejected and it wasn't", test.name)
+		}
+	}
+}
+
+func TestCheckChangeBudgetPercentWithoutState(t *testing.T) {
+	budget := ChangeBudget{MaxChangesPercent: float64Ptr(10)}
+	totals := PlanTotals{Create: 20}
+
+	for _, plan := range []*tfjson.Plan{&tfjson.Plan{}, getBudgetTestPlan(0)} {
+		err := CheckChangeBudget(plan, PlanSummary{Totals: totals}, budget)
+		if err != nil {
+			t.Errorf("Expected plan without prior resources to be accepted and it wasn't: %s", err.Error())
+		}
+	}
+}
+
+func TestChangeBudgetMerge(t *testing.T) {
+	first := ChangeBudget{MaxDeletions: int64Ptr(5), MaxChanges: int64Ptr(10), MaxChangesPercent: float64Ptr(50)}
+	second := ChangeBudget{MaxDeletions: int64Ptr(2), MaxReplacements: int64Ptr(3), MaxChanges: int64Ptr(20)}
+
+	merged := first.Merge(second)
+	if *merged.MaxDeletions != 2 || *merged.MaxReplacements != 3 || *merged.MaxChanges != 10 || *merged.MaxChangesPercent != 50 {
+		t.Errorf("Expected merged budget to keep the lowest maximums and it didn't: %v", merged)
+	}
+
+	empty := ChangeBudget{}
+	merged = empty.Merge(ChangeBudget{})
+	if merged.IsDefined() {
+		t.Errorf("Expected merging undefined budgets to produce an undefined budget and it didn't")
+	}
+}
+
+func TestChangeBudgetValidate(t *testing.T) {
+	invalid := []ChangeBudget{
+		ChangeBudget{MaxDeletions: int64Ptr(-1)},
+		ChangeBudget{MaxReplacements: int64Ptr(-1)},
+		ChangeBudget{MaxChanges: int64Ptr(-1)},
+		ChangeBudget{MaxChangesPercent: float64Ptr(-1)},
+		ChangeBudget{MaxChangesPercent: float64Ptr(100.5)},
+	}
+
+	for _, budget := range invalid {
+		if budget.Validate() == nil {
+			t.Errorf("Expected budget %v to be invalid and it wasn't", budget)
+		}
+	}
+
+	valid := ChangeBudget{MaxDeletions: int64Ptr(0), MaxChangesPercent: float64Ptr(100)}
+	if valid.Validate() != nil {
+		t.Errorf("Expected budget with a zero maximum and a 100 percent maximum to be valid and it wasn't")
+	}
+}
@@ -4,37 +4,153 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"regexp"
+	"strings"
+
 	yaml "gopkg.in/yaml.v2"
 	tfjson "github.com/hashicorp/terraform-json"
 )
 
+var instanceKeyRegex = regexp.MustCompile(`\[[^\[\]]*\]$`)
+
+func globMatch(pattern string, value string) bool {
+	var expr strings.Builder
+	expr.WriteString("^")
+	for _, char := range pattern {
+		switch char {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(char)))
+		}
+	}
+	expr.WriteString("$")
+
+	return regexp.MustCompile(expr.String()).MatchString(value)
+}
+
 type ForbiddenOperation struct {
 	Provider        string
 	ResourceAddress string			`yaml:"resource_address"`
+	ResourceType    string			`yaml:"resource_type"`
+	Module          string
 	Operations      tfjson.Actions
+	File            string			`yaml:"-"`
+}
+
+func (forOp *ForbiddenOperation) IsDefined() bool {
+	return forOp.ResourceAddress != "" || forOp.ResourceType != "" || forOp.Module != ""
+}
+
+func (forOp *ForbiddenOperation) matchesAddress(address string) bool {
+	if forOp.ResourceAddress == "" {
+		return true
+	}
+
+	return globMatch(forOp.ResourceAddress, address) || globMatch(forOp.ResourceAddress, instanceKeyRegex.ReplaceAllString(address, ""))
+}
+
+func (forOp *ForbiddenOperation) matchesType(resourceType string) bool {
+	return forOp.ResourceType == "" || globMatch(forOp.ResourceType, resourceType)
+}
+
+func (forOp *ForbiddenOperation) matchesModule(moduleAddress string) bool {
+	if forOp.Module == "" {
+		return true
+	}
+
+	return globMatch(forOp.Module, moduleAddress) || globMatch(forOp.Module + "[*", moduleAddress) || globMatch(forOp.Module + ".*", moduleAddress)
+}
+
+func (forOp *ForbiddenOperation) Matches(change *tfjson.ResourceChange) bool {
+	sameProvider := forOp.Provider == "" || forOp.Provider == change.ProviderName
+	return sameProvider && forOp.matchesAddress(change.Address) && forOp.matchesType(change.Type) && forOp.matchesModule(change.ModuleAddress)
+}
+
+type ForbiddenOperationViolation struct {
+	Address   string
+	Provider  string
+	Actions   tfjson.Actions
+	Operation ForbiddenOperation
+}
+
+func (violation *ForbiddenOperationViolation) ToString() string {
+	return fmt.Sprintf("\"%s\" (provider \"%s\", actions %v) is protected by a rule in file %s", violation.Address, violation.Provider, violation.Actions, violation.Operation.File)
+}
+
+type ForbiddenOperationsError struct {
+	Violations []ForbiddenOperationViolation
+}
+
+func (err *ForbiddenOperationsError) Error() string {
+	lines := []string{"Aborting as forbidden operations are about to be performed on protected resources:"}
+	for _, violation := range err.Violations {
+		lines = append(lines, fmt.Sprintf("  - %s", violation.ToString()))
+	}
+
+	return strings.Join(lines, "\n")
 }
 
 type ForbiddenOperationsFile struct {
 	ForbiddenOperations []ForbiddenOperation	`yaml:"forbidden_operations"`
+	ChangeBudget        ChangeBudget          `yaml:"change_budget"`
+}
+
+func readForbiddenOperationsFile(path string) (ForbiddenOperationsFile, error) {
+	var forOpsfile ForbiddenOperationsFile
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return forOpsfile, errors.New(fmt.Sprintf("Error reading forbidden operations file %s: %s", path, err.Error()))
+	}
+	err = yaml.Unmarshal(b, &forOpsfile)
+	if err != nil {
+		return forOpsfile, errors.New(fmt.Sprintf("Error parsing forbidden operations file %s: %s", path, err.Error()))
+	}
+
+	return forOpsfile, nil
 }
 
 func GetForbiddenOperations(paths []string) ([]ForbiddenOperation, error) {
 	forbiddenOps := []ForbiddenOperation{}
 	
 	for _, path := range paths {
-		var forOpsfile ForbiddenOperationsFile
-
-		b, err := ioutil.ReadFile(path)
+		forOpsfile, err := readForbiddenOperationsFile(path)
 		if err != nil {
-			return forbiddenOps, errors.New(fmt.Sprintf("Error reading forbidden operations file %s: %s", path, err.Error()))
+			return forbiddenOps, err
 		}
-		err = yaml.Unmarshal(b, &forOpsfile)
+
+		for _, forOp := range forOpsfile.ForbiddenOperations {
+			if !forOp.IsDefined() {
+				return forbiddenOps, errors.New(fmt.Sprintf("Error in forbidden operations file %s: each forbidden operation must define at least one of resource_address, resource_type or module", path))
+			}
+
+			forOp.File = path
+			forbiddenOps = append(forbiddenOps, forOp)
+		}
+	}
+
+	return forbiddenOps, nil
+}
+
+func GetChangeBudget(paths []string) (ChangeBudget, error) {
+	budget := ChangeBudget{}
+
+	for _, path := range paths {
+		forOpsfile, err := readForbiddenOperationsFile(path)
 		if err != nil {
-			return forbiddenOps, errors.New(fmt.Sprintf("Error parsing forbidden operations file %s: %s", path, err.Error()))
+			return budget, err
 		}
 
-		forbiddenOps = append(forbiddenOps, forOpsfile.ForbiddenOperations...)
+		validErr := forOpsfile.ChangeBudget.Validate()
+		if validErr != nil {
+			return budget, errors.New(fmt.Sprintf("Error in forbidden operations file %s: %s", path, validErr.Error()))
+		}
+
+		budget = budget.Merge(forOpsfile.ChangeBudget)
 	}
 
-	return forbiddenOps, nil
+	return budget, nil
 }
\ No newline at end of file
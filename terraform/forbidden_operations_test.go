@@ -0,0 +1,165 @@
+package terraform
+
+import (
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+func TestGlobMatch(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		value    string
+		expected bool
+	}{
+		{"foo", "foo", true},
+		{"foo", "foobar", false},
+		{"foo", "barfoo", false},
+		{"foo*", "foobar", true},
+		{"*bar", "foobar", true},
+		{"f*r", "foobar", true},
+		{"*", "", true},
+		{"fo?", "foo", true},
+		{"fo?", "fo", false},
+		{"fo?", "fooo", false},
+		{"aws_instance.*", "aws_instance.web", true},
+		{"aws_instance.*", "aws_instanceXweb", false},
+		{"foo[0]", "foo[0]", true},
+		{"foo[0]", "foo0", false},
+		{"foo[\"eu\"]", "foo[\"eu\"]", true},
+		{"foo(bar)+", "foo(bar)+", true},
+		{"foo(bar)+", "foobarbar", false},
+	}
+
+	for _, test := range tests {
+		if globMatch(test.pattern, test.value) != test.expected {
+			t.Errorf("Expected glob \"%s\" matching \"%s\" to be %t and it wasn't", test.pattern, test.value, test.expected)
+		}
+	}
+}
+
+func TestForbiddenOperationMatchesAddress(t *testing.T) {
+	tests := []struct {
+		rule     string
+		address  string
+		expected bool
+	}{
+		{"", "aws_instance.web", true},
+		{"aws_instance.web", "aws_instance.web", true},
+		{"aws_instance.web", "aws_instance.webserver", false},
+		//Rules without an instance key match every instance of the resource
+		{"aws_instance.web", "aws_instance.web[0]", true},
+		{"aws_instance.web", "aws_instance.web[\"eu\"]", true},
+		{"aws_instance.web", "aws_instance.web[0].extra", false},
+		//Rules with an instance key only match that instance
+		{"aws_instance.web[0]", "aws_instance.web[0]", true},
+		{"aws_instance.web[0]", "aws_instance.web[1]", false},
+		{"aws_instance.web[0]", "aws_instance.web", false},
+		{"aws_instance.web[\"eu\"]", "aws_instance.web[\"us\"]", false},
+		{"aws_instance.web[*]", "aws_instance.web[\"us\"]", true},
+		{"aws_instance.web[*]", "aws_instance.web", false},
+		//Only the instance key of the resource is ignored, not those of the modules containing it
+		{"module.db[0].aws_instance.web", "module.db[0].aws_instance.web[1]", true},
+		{"module.db.aws_instance.web", "module.db[0].aws_instance.web", false},
+		{"module.db.aws_instance.web", "module.db[0].aws_instance.web[1]", false},
+		{"module.*.aws_instance.web", "module.db[0].aws_instance.web[1]", true},
+	}
+
+	for _, test := range tests {
+		forOp := ForbiddenOperation{ResourceAddress: test.rule}
+		if forOp.matchesAddress(test.address) != test.expected {
+			t.Errorf("Expected resource_address rule \"%s\" matching \"%s\" to be %t and it wasn't", test.rule, test.address, test.expected)
+		}
+	}
+}
+
+func TestForbiddenOperationMatchesModule(t *testing.T) {
+	tests := []struct {
+		rule     string
+		module   string
+		expected bool
+	}{
+		{"", "", true},
+		{"module.db", "", false},
+		{"module.db", "module.db", true},
+		{"module.db", "module.db[0]", true},
+		{"module.db", "module.db[\"eu\"]", true},
+		{"module.db", "module.db.module.replica", true},
+		{"module.db", "module.dbs", false},
+		{"module.db[0]", "module.db[1]", false},
+		{"module.db*", "module.dbs", true},
+	}
+
+	for _, test := range tests {
+		forOp := ForbiddenOperation{Module: test.rule}
+		if forOp.matchesModule(test.module) != test.expected {
+			t.Errorf("Expected module rule \"%s\" matching \"%s\" to be %t and it wasn't", test.rule, test.module, test.expected)
+		}
+	}
+}
+
+func TestForbiddenOperationMatches(t *testing.T) {
+	change := &tfjson.ResourceChange{
+		Address: "module.db.aws_db_instance.main[0]",
+		ModuleAddress: "module.db",
+		Type: "aws_db_instance",
+		ProviderName: "registry.terraform.io/hashicorp/aws",
+	}
+
+	tests := []struct {
+		forOp    ForbiddenOperation
+		expected bool
+	}{
+		{ForbiddenOperation{ResourceType: "aws_db_instance"}, true},
+		{ForbiddenOperation{ResourceType: "aws_db_*"}, true},
+		{ForbiddenOperation{ResourceType: "aws_instance"}, false},
+		{ForbiddenOperation{Module: "module.db", ResourceType: "aws_db_instance"}, true},
+		{ForbiddenOperation{Module: "module.other", ResourceType: "aws_db_instance"}, false},
+		{ForbiddenOperation{Provider: "registry.terraform.io/hashicorp/aws", ResourceAddress: "module.db.aws_db_instance.main"}, true},
+		{ForbiddenOperation{Provider: "registry.terraform.io/hashicorp/google", ResourceAddress: "module.db.aws_db_instance.main"}, false},
+	}
+
+	for _, test := range tests {
+		if test.forOp.Matches(change) != test.expected {
+			t.Errorf("Expected rule %v matching \"%s\" to be %t and it wasn't", test.forOp, change.Address, test.expected)
+		}
+	}
+}
+
+func TestCheckPlan(t *testing.T) {
+	plan := &tfjson.Plan{
+		ResourceChanges: []*tfjson.ResourceChange{
+			&tfjson.ResourceChange{
+				Address: "aws_instance.web[0]",
+				Type: "aws_instance",
+				Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionDelete, tfjson.ActionCreate}},
+			},
+			&tfjson.ResourceChange{
+				Address: "aws_instance.web[1]",
+				Type: "aws_instance",
+				Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionUpdate}},
+			},
+		},
+	}
+
+	err := CheckPlan(plan, []ForbiddenOperation{
+		ForbiddenOperation{ResourceAddress: "aws_instance.web", Operations: tfjson.Actions{tfjson.ActionDelete}, File: "protect.terracd-forbidden.yml"},
+	})
+	forOpsErr, ok := err.(*ForbiddenOperationsError)
+	if !ok {
+		t.Errorf("Expected plan deleting an instance of a protected resource to be rejected and it wasn't")
+		return
+	}
+
+	if len(forOpsErr.Violations) != 1 || forOpsErr.Violations[0].Address != "aws_instance.web[0]" {
+		t.Errorf("Expected a single violation for \"aws_instance.web[0]\" and got %v", forOpsErr.Violations)
+	}
+
+	err = CheckPlan(plan, []ForbiddenOperation{
+		ForbiddenOperation{ResourceAddress: "aws_instance.web[1]", Operations: tfjson.Actions{tfjson.ActionDelete}},
+		ForbiddenOperation{ResourceAddress: "aws_instance.db", Operations: tfjson.Actions{tfjson.ActionDelete, tfjson.ActionUpdate}},
+	})
+	if err != nil {
+		t.Errorf("Expected plan not touching protected operations to be accepted and it wasn't: %s", err.Error())
+	}
+}
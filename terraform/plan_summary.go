@@ -0,0 +1,105 @@
+package terraform
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+type ResourceChangeSummary struct {
+	Address  string   `json:"address"`
+	Provider string   `json:"provider"`
+	Actions  []string `json:"actions"`
+}
+
+type PlanTotals struct {
+	Create  int64 `json:"create"`
+	Update  int64 `json:"update"`
+	Delete  int64 `json:"delete"`
+	Replace int64 `json:"replace"`
+}
+
+func (totals *PlanTotals) ToMap() map[string]int64 {
+	return map[string]int64{
+		"create": totals.Create,
+		"update": totals.Update,
+		"delete": totals.Delete,
+		"replace": totals.Replace,
+	}
+}
+
+type PlanSummary struct {
+	Changes []ResourceChangeSummary `json:"changes"`
+	Totals  PlanTotals              `json:"totals"`
+}
+
+func SummarizePlan(plan *tfjson.Plan) PlanSummary {
+	summary := PlanSummary{Changes: []ResourceChangeSummary{}}
+
+	for _, change := range plan.ResourceChanges {
+		if change.Change == nil {
+			continue
+		}
+
+		actions := change.Change.Actions
+		if actions.NoOp() || actions.Read() {
+			continue
+		}
+
+		actionsStr := []string{}
+		for _, action := range actions {
+			actionsStr = append(actionsStr, string(action))
+		}
+
+		summary.Changes = append(summary.Changes, ResourceChangeSummary{
+			Address: change.Address,
+			Provider: change.ProviderName,
+			Actions: actionsStr,
+		})
+
+		if actions.Replace() {
+			summary.Totals.Replace += 1
+		} else if actions.Create() {
+			summary.Totals.Create += 1
+		} else if actions.Update() {
+			summary.Totals.Update += 1
+		} else if actions.Delete() {
+			summary.Totals.Delete += 1
+		}
+	}
+
+	return summary
+}
+
+func (summary *PlanSummary) Print() {
+	fmt.Println("Info: Plan summary:")
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ADDRESS\tPROVIDER\tACTIONS")
+	for _, change := range summary.Changes {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", change.Address, change.Provider, strings.Join(change.Actions, ","))
+	}
+	w.Flush()
+
+	fmt.Printf("Create: %d, Update: %d, Delete: %d, Replace: %d\n", summary.Totals.Create, summary.Totals.Update, summary.Totals.Delete, summary.Totals.Replace)
+}
+
+func (summary *PlanSummary) Save(filePath string) error {
+	output, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error serializing the plan summary: %s", err.Error()))
+	}
+
+	err = ioutil.WriteFile(filePath, output, 0600)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error writing the plan summary file \"%s\": %s", filePath, err.Error()))
+	}
+
+	return nil
+}
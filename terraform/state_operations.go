@@ -0,0 +1,73 @@
+package terraform
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+type ImportOperation struct {
+	Address string
+	Id      string
+}
+
+type MoveOperation struct {
+	Source      string
+	Destination string
+}
+
+type RemoveOperation struct {
+	Address string
+}
+
+//Operations on the terraform state executed by the import, state_mv and state_rm commands respectively
+type StateOperations struct {
+	Imports  []ImportOperation
+	Moves    []MoveOperation
+	Removals []RemoveOperation
+}
+
+func (ops *StateOperations) Validate() error {
+	for idx, imp := range ops.Imports {
+		if imp.Address == "" || imp.Id == "" {
+			return errors.New(fmt.Sprintf("Import %d must define both an address and an id", idx))
+		}
+	}
+
+	for idx, move := range ops.Moves {
+		if move.Source == "" || move.Destination == "" {
+			return errors.New(fmt.Sprintf("Move %d must define both a source and a destination", idx))
+		}
+	}
+
+	for idx, removal := range ops.Removals {
+		if removal.Address == "" {
+			return errors.New(fmt.Sprintf("Removal %d must define an address", idx))
+		}
+	}
+
+	return nil
+}
+
+func ReadStateOperationsFile(path string) (StateOperations, error) {
+	var ops StateOperations
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ops, errors.New(fmt.Sprintf("Error reading state operations file %s: %s", path, err.Error()))
+	}
+
+	err = yaml.UnmarshalStrict(b, &ops)
+	if err != nil {
+		return ops, errors.New(fmt.Sprintf("Error parsing state operations file %s: %s", path, err.Error()))
+	}
+
+	validErr := ops.Validate()
+	if validErr != nil {
+		return ops, errors.New(fmt.Sprintf("Error in state operations file %s: %s", path, validErr.Error()))
+	}
+
+	return ops, nil
+}
@@ -12,15 +12,14 @@ import (
 	tfjson "github.com/hashicorp/terraform-json"
 )
 
-func getContext(timeout time.Duration) (context.Context, context.CancelFunc) {
-	ctx := context.Background()
+func getContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
 	if int64(timeout) == int64(0) {
 		return context.WithCancel(ctx)
 	}
 	return context.WithTimeout(ctx, timeout)
 }
 
-func Init(dir string, terraformPath string, timeout time.Duration) error {
+func Init(ctx context.Context, dir string, terraformPath string, timeout time.Duration) error {
 	tf, err := tfexec.NewTerraform(dir, terraformPath)
 	if err != nil {
 		return errors.New(fmt.Sprintf("Error preparing terraform in directory \"%s\": %s", dir, err.Error()))
@@ -29,7 +28,7 @@ func Init(dir string, terraformPath string, timeout time.Duration) error {
 	tf.SetStdout(os.Stdout)
 	tf.SetStderr(os.Stderr)
 
-	ctx, cancel := getContext(timeout)
+	ctx, cancel := getContext(ctx, timeout)
 	defer cancel()
 
 	initErr := tf.Init(ctx, tfexec.Upgrade(true))
@@ -40,7 +39,36 @@ func Init(dir string, terraformPath string, timeout time.Duration) error {
 	return nil
 }
 
-func Plan(dir string, planName string, terraformPath string, timeout time.Duration) (bool, error) {
+type PlanOptions struct {
+	RefreshOnly bool
+	Destroy     bool
+	Targets     []string
+	Replace     []string
+}
+
+func (opts *PlanOptions) getTfexecOptions(planFile string) []tfexec.PlanOption {
+	tfOpts := []tfexec.PlanOption{tfexec.Out(planFile)}
+
+	if opts.RefreshOnly {
+		tfOpts = append(tfOpts, tfexec.RefreshOnly(true))
+	}
+
+	if opts.Destroy {
+		tfOpts = append(tfOpts, tfexec.Destroy(true))
+	}
+
+	for _, target := range opts.Targets {
+		tfOpts = append(tfOpts, tfexec.Target(target))
+	}
+
+	for _, address := range opts.Replace {
+		tfOpts = append(tfOpts, tfexec.Replace(address))
+	}
+
+	return tfOpts
+}
+
+func Plan(ctx context.Context, dir string, planName string, terraformPath string, timeout time.Duration, opts PlanOptions) (bool, error) {
 	tf, err := tfexec.NewTerraform(dir, terraformPath)
 	if err != nil {
 		return false, errors.New(fmt.Sprintf("Error preparing terraform in directory \"%s\": %s", dir, err.Error()))
@@ -49,10 +77,10 @@ func Plan(dir string, planName string, terraformPath string, timeout time.Durati
 	tf.SetStdout(os.Stdout)
 	tf.SetStderr(os.Stderr)
 
-	ctx, cancel := getContext(timeout)
+	ctx, cancel := getContext(ctx, timeout)
 	defer cancel()
 
-	changes, planErr := tf.Plan(ctx, tfexec.Out(path.Join(dir, planName)))
+	changes, planErr := tf.Plan(ctx, opts.getTfexecOptions(path.Join(dir, planName))...)
 	if planErr != nil {
 		return false, errors.New(fmt.Sprintf("Error with terraform plan in directory \"%s\": %s", dir, planErr.Error()))
 	}
@@ -60,7 +88,7 @@ func Plan(dir string, planName string, terraformPath string, timeout time.Durati
 	return changes, nil
 }
 
-func Apply(dir string, planName string, terraformPath string, timeout time.Duration) error {
+func Apply(ctx context.Context, dir string, planName string, terraformPath string, timeout time.Duration) error {
 	tf, err := tfexec.NewTerraform(dir, terraformPath)
 	if err != nil {
 		return errors.New(fmt.Sprintf("Error preparing terraform in directory \"%s\": %s", dir, err.Error()))
@@ -69,7 +97,7 @@ func Apply(dir string, planName string, terraformPath string, timeout time.Durat
 	tf.SetStdout(os.Stdout)
 	tf.SetStderr(os.Stderr)
 
-	ctx, cancel := getContext(timeout)
+	ctx, cancel := getContext(ctx, timeout)
 	defer cancel()
 
 	applyErr := tf.Apply(ctx, tfexec.DirOrPlan(path.Join(dir, planName)))
@@ -80,24 +108,26 @@ func Apply(dir string, planName string, terraformPath string, timeout time.Durat
 	return nil
 }
 
-func Destroy(dir string, terraformPath string, timeout time.Duration) error {
+type Outputs map[string]tfexec.OutputMeta
+
+func Output(ctx context.Context, dir string, terraformPath string, timeout time.Duration) (Outputs, error) {
 	tf, err := tfexec.NewTerraform(dir, terraformPath)
 	if err != nil {
-		return errors.New(fmt.Sprintf("Error preparing terraform in directory \"%s\": %s", dir, err.Error()))
+		return nil, errors.New(fmt.Sprintf("Error preparing terraform in directory \"%s\": %s", dir, err.Error()))
 	}
 
-	tf.SetStdout(os.Stdout)
+	//Stdout is deliberately not forwarded as the json output includes the values of sensitive outputs
 	tf.SetStderr(os.Stderr)
 
-	ctx, cancel := getContext(timeout)
+	ctx, cancel := getContext(ctx, timeout)
 	defer cancel()
 
-	destroyErr := tf.Destroy(ctx)
-	if destroyErr != nil {
-		return errors.New(fmt.Sprintf("Error with terraform destroy in directory \"%s\": %s", dir, destroyErr.Error()))
+	outputs, outputErr := tf.Output(ctx)
+	if outputErr != nil {
+		return nil, errors.New(fmt.Sprintf("Error with terraform output in directory \"%s\": %s", dir, outputErr.Error()))
 	}
 
-	return nil
+	return Outputs(outputs), nil
 }
 
 func operationsInsersect(a tfjson.Actions, b tfjson.Actions) bool {
@@ -112,31 +142,44 @@ func operationsInsersect(a tfjson.Actions, b tfjson.Actions) bool {
 	return false
 }
 
-func CheckPlan(dir string, planName string, terraformPath string, forbiddenOps []ForbiddenOperation) error {
+func ShowPlan(ctx context.Context, dir string, planName string, terraformPath string) (*tfjson.Plan, error) {
 	tf, err := tfexec.NewTerraform(dir, terraformPath)
 	if err != nil {
-		return errors.New(fmt.Sprintf("Error preparing terraform in directory \"%s\": %s", dir, err.Error()))
+		return nil, errors.New(fmt.Sprintf("Error preparing terraform in directory \"%s\": %s", dir, err.Error()))
 	}
 
-	plan, planErr := tf.ShowPlanFile(context.Background(), path.Join(dir, planName))
+	plan, planErr := tf.ShowPlanFile(ctx, path.Join(dir, planName))
 	if planErr != nil {
-		return errors.New(fmt.Sprintf("Error occured while reading/parsing the plan file: %s", planErr.Error()))
+		return nil, errors.New(fmt.Sprintf("Error occured while reading/parsing the plan file: %s", planErr.Error()))
 	}
 
+	return plan, nil
+}
+
+func CheckPlan(plan *tfjson.Plan, forbiddenOps []ForbiddenOperation) error {
+	violations := []ForbiddenOperationViolation{}
+
 	for _, change := range plan.ResourceChanges {
 		for _, forOp := range forbiddenOps {
-			sameProvider := forOp.Provider == "" || forOp.Provider == change.ProviderName
-			sameAddress := forOp.ResourceAddress == change.Address
-			if sameProvider && sameAddress && operationsInsersect(forOp.Operations, (*change.Change).Actions) {
-				return errors.New(fmt.Sprintf("Aborting as forbidden operation is about to be performed on protected resource \"%s\"", forOp.ResourceAddress))
+			if forOp.Matches(change) && operationsInsersect(forOp.Operations, (*change.Change).Actions) {
+				violations = append(violations, ForbiddenOperationViolation{
+					Address: change.Address,
+					Provider: change.ProviderName,
+					Actions: (*change.Change).Actions,
+					Operation: forOp,
+				})
 			}
 		}
 	}
 
+	if len(violations) > 0 {
+		return &ForbiddenOperationsError{Violations: violations}
+	}
+
 	return nil
 }
 
-func StatePull(dir string, stateFile string, terraformPath string, timeout time.Duration) (error) {
+func StatePull(ctx context.Context, dir string, stateFile string, terraformPath string, timeout time.Duration) (error) {
 	tf, err := tfexec.NewTerraform(dir, terraformPath)
 	if err != nil {
 		return errors.New(fmt.Sprintf("Error preparing terraform in directory \"%s\": %s", dir, err.Error()))
@@ -145,7 +188,7 @@ func StatePull(dir string, stateFile string, terraformPath string, timeout time.
 	tf.SetStdout(os.Stdout)
 	tf.SetStderr(os.Stderr)
 
-	ctx, cancel := getContext(timeout)
+	ctx, cancel := getContext(ctx, timeout)
 	defer cancel()
 
 	state, pullErr := tf.StatePull(ctx)
@@ -168,7 +211,7 @@ func StatePull(dir string, stateFile string, terraformPath string, timeout time.
 	return nil
 }
 
-func StatePush(dir string, stateFile string, terraformPath string, timeout time.Duration) error {
+func StatePush(ctx context.Context, dir string, stateFile string, terraformPath string, timeout time.Duration) error {
 	tf, err := tfexec.NewTerraform(dir, terraformPath)
 	if err != nil {
 		return errors.New(fmt.Sprintf("Error preparing terraform in directory \"%s\": %s", dir, err.Error()))
@@ -177,7 +220,7 @@ func StatePush(dir string, stateFile string, terraformPath string, timeout time.
 	tf.SetStdout(os.Stdout)
 	tf.SetStderr(os.Stderr)
 
-	ctx, cancel := getContext(timeout)
+	ctx, cancel := getContext(ctx, timeout)
 	defer cancel()
 
 	pushErr := tf.StatePush(ctx, stateFile)
@@ -186,4 +229,64 @@ func StatePush(dir string, stateFile string, terraformPath string, timeout time.
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+func Import(ctx context.Context, dir string, address string, id string, terraformPath string, timeout time.Duration) error {
+	tf, err := tfexec.NewTerraform(dir, terraformPath)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error preparing terraform in directory \"%s\": %s", dir, err.Error()))
+	}
+
+	tf.SetStdout(os.Stdout)
+	tf.SetStderr(os.Stderr)
+
+	ctx, cancel := getContext(ctx, timeout)
+	defer cancel()
+
+	importErr := tf.Import(ctx, address, id)
+	if importErr != nil {
+		return errors.New(fmt.Sprintf("Error with terraform import of \"%s\" in directory \"%s\": %s", address, dir, importErr.Error()))
+	}
+
+	return nil
+}
+
+func StateMv(ctx context.Context, dir string, source string, destination string, terraformPath string, timeout time.Duration) error {
+	tf, err := tfexec.NewTerraform(dir, terraformPath)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error preparing terraform in directory \"%s\": %s", dir, err.Error()))
+	}
+
+	tf.SetStdout(os.Stdout)
+	tf.SetStderr(os.Stderr)
+
+	ctx, cancel := getContext(ctx, timeout)
+	defer cancel()
+
+	mvErr := tf.StateMv(ctx, source, destination)
+	if mvErr != nil {
+		return errors.New(fmt.Sprintf("Error with terraform state mv of \"%s\" to \"%s\" in directory \"%s\": %s", source, destination, dir, mvErr.Error()))
+	}
+
+	return nil
+}
+
+func StateRm(ctx context.Context, dir string, address string, terraformPath string, timeout time.Duration) error {
+	tf, err := tfexec.NewTerraform(dir, terraformPath)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error preparing terraform in directory \"%s\": %s", dir, err.Error()))
+	}
+
+	tf.SetStdout(os.Stdout)
+	tf.SetStderr(os.Stderr)
+
+	ctx, cancel := getContext(ctx, timeout)
+	defer cancel()
+
+	rmErr := tf.StateRm(ctx, address)
+	if rmErr != nil {
+		return errors.New(fmt.Sprintf("Error with terraform state rm of \"%s\" in directory \"%s\": %s", address, dir, rmErr.Error()))
+	}
+
+	return nil
+}
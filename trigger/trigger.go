@@ -0,0 +1,81 @@
+package trigger
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Ferlab-Ste-Justine/terracd/s3"
+	"github.com/Ferlab-Ste-Justine/terracd/state"
+)
+
+//Name of the etcd key (after the prefix) or s3 object (under the path) holding the trigger value
+const TriggerObject = "trigger"
+
+const watchRetryInterval = 10 * time.Second
+
+//External key whose value change forces the next execution
+type TriggerConfig struct {
+	Etcd state.EtcdConfig
+	S3   s3.S3ClientConfig
+}
+
+func (conf *TriggerConfig) IsDefined() bool {
+	return conf.Etcd.IsDefined() || conf.S3.IsDefined()
+}
+
+func (conf *TriggerConfig) Validate() error {
+	if conf.Etcd.IsDefined() && conf.S3.IsDefined() {
+		return errors.New("Only one of etcd or s3 can be defined for the trigger")
+	}
+
+	return nil
+}
+
+func (conf *TriggerConfig) getStore() state.StateStore {
+	if conf.Etcd.IsDefined() {
+		return &state.EtcdStateStore{Config: conf.Etcd}
+	}
+
+	return &state.S3StateStore{Config: conf.S3}
+}
+
+//Returns the current value of the trigger. The value is empty if the trigger key does not exist.
+func (conf *TriggerConfig) Read() (string, error) {
+	store := conf.getStore()
+	initErr := store.Initialize()
+	if initErr != nil {
+		return "", errors.New(fmt.Sprintf("Error connecting to the trigger store: %s", initErr.Error()))
+	}
+	defer store.Cleanup()
+
+	value, _, readErr := store.ReadObject(TriggerObject)
+	if readErr != nil {
+		return "", errors.New(fmt.Sprintf("Error reading the trigger: %s", readErr.Error()))
+	}
+
+	return strings.TrimSpace(string(value)), nil
+}
+
+//Returns whether the trigger can be watched for changes, which is only supported for etcd
+func (conf *TriggerConfig) IsWatchable() bool {
+	return conf.Etcd.IsDefined()
+}
+
+//Calls the notification function each time the value of the trigger is written. Never returns, reconnecting if the watch fails.
+func (conf *TriggerConfig) Watch(notify func()) {
+	for {
+		store := &state.EtcdStateStore{Config: conf.Etcd}
+		watchErr := store.Initialize()
+		if watchErr == nil {
+			watchErr = store.WatchObject(TriggerObject, func(value []byte) {
+				notify()
+			})
+			store.Cleanup()
+		}
+
+		fmt.Printf("Warning: Watch on the trigger failed: %s. Will retry in %s.\n", watchErr.Error(), watchRetryInterval.String())
+		time.Sleep(watchRetryInterval)
+	}
+}